@@ -0,0 +1,224 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/someview/go-metrics/clock/clocktest"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/someview/go-metrics/sample"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("http.latency p99 > 250ms for 2m")
+	require.NoError(t, err)
+	assert.Equal(t, Rule{
+		Expr:      "http.latency p99 > 250ms for 2m",
+		Metric:    "http.latency",
+		Stat:      "p99",
+		Op:        Greater,
+		Threshold: float64(250 * time.Millisecond),
+		For:       2 * time.Minute,
+	}, rule)
+
+	rule, err = ParseRule("errors rate > 300/m")
+	require.NoError(t, err)
+	assert.Equal(t, StatRate, rule.Stat)
+	assert.Equal(t, 5.0, rule.Threshold)
+	assert.Equal(t, time.Duration(0), rule.For)
+
+	rule, err = ParseRule("queue.len >= 10")
+	require.NoError(t, err)
+	assert.Equal(t, StatValue, rule.Stat)
+	assert.Equal(t, GreaterEqual, rule.Op)
+	assert.Equal(t, 10.0, rule.Threshold)
+
+	for _, expr := range []string{
+		"",
+		"errors > ",
+		"errors rate",
+		"errors p101 > 1",
+		"errors median > 1",
+		"errors => 1",
+		"errors > ten",
+		"errors > 5/fortnight",
+		"errors > 1 for ever",
+		"errors > 1 until 2m",
+	} {
+		_, err := ParseRule(expr)
+		assert.Error(t, err, expr)
+	}
+	assert.Panics(t, func() { MustParseRule("errors") })
+}
+
+func TestRuleValue(t *testing.T) {
+	s := reporter.NewRegistrySnapshot()
+	s.Counters["errors"] = 30
+	s.Gauges["inflight"] = 4
+	s.Gauges["queued"] = 50
+	s.GaugeFloat64s["load"] = 0.5
+	h := sample.NewSlidingWindowSample(100)
+	for i := int64(1); i <= 100; i++ {
+		h.Update(i)
+	}
+	s.Histograms["latency"] = h.Snapshot()
+	s.Histograms["idle"] = sample.NewSlidingWindowSample(100).Snapshot()
+	prev := reporter.NewRegistrySnapshot()
+	prev.Counters["errors"] = 10
+
+	value := func(expr string) (float64, bool) {
+		return MustParseRule(expr+" > 0").value(s, prev, 10*time.Second)
+	}
+	for expr, expected := range map[string]float64{
+		"errors":         30,
+		"errors rate":    2,
+		"inflight value": 4,
+		"load":           0.5,
+		"latency count":  100,
+		"latency rate":   10,
+		"latency min":    1,
+		"latency max":    100,
+		"latency mean":   50.5,
+		"latency sum":    5050,
+		"latency p50":    50.5,
+		"idle count":     0,
+	} {
+		v, ok := value(expr)
+		assert.True(t, ok, expr)
+		assert.Equal(t, expected, v, expr)
+	}
+	for _, expr := range []string{"missing", "queued rate", "load rate", "latency", "errors p99", "idle p99"} {
+		_, ok := value(expr)
+		assert.False(t, ok, expr)
+	}
+	_, ok := MustParseRule("errors rate > 0").value(s, nil, 0)
+	assert.False(t, ok, "rates need a previous snapshot")
+}
+
+func TestEngine(t *testing.T) {
+	c := clocktest.NewClock(time.Unix(0, 0))
+	var notified []Alert
+	e := NewEngine([]Rule{
+		MustParseRule("inflight > 10 for 2m"),
+		MustParseRule("errors rate > 1/s"),
+	}, WithClock(c), WithNotifiers(NotifierFunc(func(_ context.Context, a Alert) error {
+		notified = append(notified, a)
+		return nil
+	})))
+	failures := int64(0)
+	evaluate := func(inflight int64) {
+		s := reporter.NewRegistrySnapshot()
+		s.Gauges["inflight"] = inflight
+		s.Counters["errors"] = failures
+		require.NoError(t, e.Evaluate(context.Background(), s))
+		c.Add(time.Minute)
+	}
+	states := func() []State {
+		var states []State
+		for _, a := range e.Alerts() {
+			states = append(states, a.State)
+		}
+		return states
+	}
+
+	evaluate(5)
+	assert.Equal(t, []State{Inactive, Inactive}, states())
+	evaluate(20)
+	assert.Equal(t, []State{Pending, Inactive}, states())
+	assert.Equal(t, time.Unix(60, 0), e.Alerts()[0].ActiveSince)
+	evaluate(5)
+	assert.Equal(t, []State{Inactive, Inactive}, states(), "pending alerts reset")
+	evaluate(20)
+	evaluate(20)
+	assert.Equal(t, []State{Pending, Inactive}, states())
+	assert.Empty(t, notified)
+	failures += 120
+	evaluate(20)
+	assert.Equal(t, []State{Firing, Firing}, states())
+	require.Len(t, notified, 2)
+	assert.Equal(t, "inflight > 10 for 2m", notified[0].Name)
+	assert.Equal(t, 20.0, notified[0].Value)
+	assert.Equal(t, time.Unix(300, 0), notified[0].At)
+	assert.Equal(t, 2.0, notified[1].Value)
+
+	notified = nil
+	evaluate(20)
+	assert.Equal(t, []State{Firing, Resolved}, states())
+	require.Len(t, notified, 1)
+	assert.Equal(t, Resolved, notified[0].State)
+	assert.Equal(t, 0.0, notified[0].Value)
+}
+
+func TestEngine_NotifierErrors(t *testing.T) {
+	fail := NotifierFunc(func(context.Context, Alert) error { return errors.New("unreachable") })
+	e := NewEngine([]Rule{MustParseRule("up == 0")}, WithNotifiers(fail))
+	s := reporter.NewRegistrySnapshot()
+	s.Gauges["up"] = 0
+	assert.EqualError(t, e.Evaluate(context.Background(), s), "unreachable")
+	assert.NoError(t, e.Evaluate(context.Background(), s), "notifies on changes only")
+}
+
+func TestEngine_Run(t *testing.T) {
+	c := clocktest.NewClock(time.Unix(0, 0))
+	r := reporter.NewRegistry()
+	reporter.GetOrRegisterGauge("up", r)
+	fired := make(chan Alert, 1)
+	e := NewEngine([]Rule{MustParseRule("up value == 0")}, WithClock(c), WithNotifiers(NotifierFunc(func(_ context.Context, a Alert) error {
+		fired <- a
+		return nil
+	})))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go e.Run(ctx, r, time.Second)
+	c.BlockUntil(1)
+	c.Add(time.Second)
+	assert.Equal(t, Firing, (<-fired).State)
+}
+
+func TestLogNotifier(t *testing.T) {
+	var buf bytes.Buffer
+	n := LogNotifier(slog.New(slog.NewTextHandler(&buf, nil)))
+	require.NoError(t, n.Notify(context.Background(), Alert{Name: "errors", Expr: "errors > 1", State: Firing, Value: 2}))
+	assert.Contains(t, buf.String(), `level=WARN msg="alert firing" name=errors expr="errors > 1" value=2`)
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var received map[string]any
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	n := WebhookNotifier(srv.URL, nil)
+	a := Alert{Name: "errors", Expr: "errors > 1", State: Resolved, Value: 0.5, At: time.Unix(60, 0).UTC()}
+	require.NoError(t, n.Notify(context.Background(), a))
+	assert.Equal(t, "resolved", received["state"])
+	assert.Equal(t, "errors", received["name"])
+	assert.Equal(t, 0.5, received["value"])
+	assert.Equal(t, "1970-01-01T00:01:00Z", received["at"])
+
+	a.Value = math.Inf(1)
+	require.NoError(t, n.Notify(context.Background(), a))
+	assert.Contains(t, received, "value")
+	assert.Nil(t, received["value"])
+
+	status = http.StatusBadGateway
+	err := n.Notify(context.Background(), a)
+	require.Error(t, err)
+	assert.True(t, strings.HasSuffix(err.Error(), "502 Bad Gateway"))
+}
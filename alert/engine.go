@@ -0,0 +1,210 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/someview/go-metrics/clock"
+	"github.com/someview/go-metrics/reporter"
+)
+
+// State is the state of an alert.
+type State int
+
+// States of an alert.  An alert is Inactive until its rule's condition
+// holds, Pending while it has held for less than the rule's duration, Firing
+// once it has held for longer, and Resolved when it stops holding after
+// firing.
+const (
+	Inactive State = iota
+	Pending
+	Firing
+	Resolved
+)
+
+var stateNames = [...]string{"inactive", "pending", "firing", "resolved"}
+
+func (s State) String() string {
+	if s < 0 || int(s) >= len(stateNames) {
+		return "unknown"
+	}
+	return stateNames[s]
+}
+
+// MarshalText encodes the state as its name.
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Alert is the state of a rule after an evaluation.
+type Alert struct {
+	Name  string `json:"name"`
+	Expr  string `json:"expr"`
+	State State  `json:"state"`
+	// Value is the latest value of the rule's statistic, kept while the
+	// metric is missing.
+	Value float64 `json:"value"`
+	// ActiveSince is when the rule's condition started holding, zero while
+	// it does not.
+	ActiveSince time.Time `json:"active_since"`
+	// At is when the alert changed state.
+	At   time.Time `json:"at"`
+	Rule Rule      `json:"-"`
+}
+
+// MarshalJSON encodes the alert with its JSON field names, and a Value that
+// is NaN or infinite, which JSON cannot represent, as null.
+func (a Alert) MarshalJSON() ([]byte, error) {
+	type alert Alert
+	v := struct {
+		alert
+		Value *float64 `json:"value"`
+	}{alert: alert(a)}
+	if !math.IsNaN(a.Value) && !math.IsInf(a.Value, 0) {
+		v.Value = &a.Value
+	}
+	return json.Marshal(v)
+}
+
+// Engine evaluates rules against registry snapshots and notifies when
+// alerts fire and resolve.  It is safe for concurrent use.
+type Engine struct {
+	notifiers []Notifier
+	clock     clock.Clock
+	onError   func(error)
+
+	mutex  sync.Mutex
+	alerts []Alert
+	prev   *reporter.RegistrySnapshot
+	prevAt time.Time
+}
+
+// Option configures an Engine.
+type Option func(*Engine)
+
+// WithNotifiers adds notifiers called when alerts fire and resolve.
+func WithNotifiers(n ...Notifier) Option {
+	return func(e *Engine) {
+		e.notifiers = append(e.notifiers, n...)
+	}
+}
+
+// WithClock sets the clock used to time evaluations and schedule Run.
+func WithClock(c clock.Clock) Option {
+	return func(e *Engine) {
+		e.clock = c
+	}
+}
+
+// WithErrorHandler sets the function Run passes notifier errors to.  They
+// are dropped by default.
+func WithErrorHandler(f func(error)) Option {
+	return func(e *Engine) {
+		e.onError = f
+	}
+}
+
+// NewEngine constructs an Engine evaluating rules, all of them inactive.
+func NewEngine(rules []Rule, opts ...Option) *Engine {
+	e := &Engine{clock: clock.System()}
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.alerts = make([]Alert, len(rules))
+	for i, rule := range rules {
+		e.alerts[i] = Alert{Name: rule.name(), Expr: rule.Expr, Rule: rule}
+	}
+	return e
+}
+
+// Alerts returns the state of every rule, in the order they were given.
+func (e *Engine) Alerts() []Alert {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]Alert(nil), e.alerts...)
+}
+
+// Evaluate evaluates every rule against s, a snapshot taken now, and calls
+// the notifiers for the alerts that fired or resolved, returning their
+// errors.  Rates are computed against the snapshot of the previous
+// evaluation; rules whose metric is missing from s, whose statistic does not
+// apply to the metric, or that need a rate on the first evaluation, are
+// evaluated as not holding.
+func (e *Engine) Evaluate(ctx context.Context, s *reporter.RegistrySnapshot) error {
+	now := e.clock.Now()
+	e.mutex.Lock()
+	var elapsed time.Duration
+	if e.prev != nil {
+		elapsed = now.Sub(e.prevAt)
+	}
+	var changed []Alert
+	for i := range e.alerts {
+		a := &e.alerts[i]
+		v, ok := a.Rule.value(s, e.prev, elapsed)
+		if ok {
+			a.Value = v
+		}
+		if a.transition(ok && a.Rule.Op.compare(v, a.Rule.Threshold), now) {
+			changed = append(changed, *a)
+		}
+	}
+	e.prev, e.prevAt = s, now
+	e.mutex.Unlock()
+
+	var errs []error
+	for _, a := range changed {
+		for _, n := range e.notifiers {
+			if err := n.Notify(ctx, a); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// transition moves a to its next state and reports whether it fired or
+// resolved.
+func (a *Alert) transition(holds bool, now time.Time) bool {
+	if !holds {
+		a.ActiveSince = time.Time{}
+		switch a.State {
+		case Pending:
+			a.State, a.At = Inactive, now
+		case Firing:
+			a.State, a.At = Resolved, now
+			return true
+		}
+		return false
+	}
+	if a.State != Pending && a.State != Firing {
+		a.ActiveSince = now
+		a.State, a.At = Pending, now
+	}
+	if a.State == Pending && now.Sub(a.ActiveSince) >= a.Rule.For {
+		a.State, a.At = Firing, now
+		return true
+	}
+	return false
+}
+
+// Run evaluates the rules against a snapshot of r, taken with
+// reporter.SnapshotRegistry, at each interval until ctx is done.  See
+// SnapshotRegistry for sharing r with other readers.
+func (e *Engine) Run(ctx context.Context, r reporter.Registry, interval time.Duration) {
+	ticker := e.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if err := e.Evaluate(ctx, reporter.SnapshotRegistry(r)); err != nil && e.onError != nil {
+				e.onError(err)
+			}
+		}
+	}
+}
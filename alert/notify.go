@@ -0,0 +1,69 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// Notifier is told when alerts fire and resolve.
+type Notifier interface {
+	Notify(ctx context.Context, a Alert) error
+}
+
+// NotifierFunc adapts a function to a Notifier.
+type NotifierFunc func(ctx context.Context, a Alert) error
+
+// Notify calls f.
+func (f NotifierFunc) Notify(ctx context.Context, a Alert) error { return f(ctx, a) }
+
+// LogNotifier logs firing alerts at slog.LevelWarn and resolved ones at
+// slog.LevelInfo.  A nil logger logs to slog.Default() at the time of
+// logging.
+func LogNotifier(l *slog.Logger) Notifier {
+	return NotifierFunc(func(ctx context.Context, a Alert) error {
+		logger := l
+		if logger == nil {
+			logger = slog.Default()
+		}
+		level := slog.LevelInfo
+		if a.State == Firing {
+			level = slog.LevelWarn
+		}
+		logger.LogAttrs(ctx, level, "alert "+a.State.String(),
+			slog.String("name", a.Name), slog.String("expr", a.Expr), slog.Float64("value", a.Value))
+		return nil
+	})
+}
+
+// WebhookNotifier posts each alert as JSON to url, e.g. a local relay
+// forwarding it to a chat room.  A nil client uses http.DefaultClient.
+// Responses with a status other than 2xx are errors.
+func WebhookNotifier(url string, client *http.Client) Notifier {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return NotifierFunc(func(ctx context.Context, a Alert) error {
+		body, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("alert: webhook %s: %s", url, resp.Status)
+		}
+		return nil
+	})
+}
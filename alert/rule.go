@@ -0,0 +1,266 @@
+// Package alert evaluates threshold rules against snapshots of a
+// reporter.Registry and notifies when alerts start and stop firing, for
+// programs too small to warrant an external alerting pipeline.
+package alert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/someview/go-metrics/reporter"
+)
+
+// Op compares a metric's value with a rule's threshold.
+type Op string
+
+// Comparison operators.
+const (
+	Greater      Op = ">"
+	GreaterEqual Op = ">="
+	Less         Op = "<"
+	LessEqual    Op = "<="
+	Equal        Op = "=="
+	NotEqual     Op = "!="
+)
+
+func (op Op) compare(v, threshold float64) bool {
+	switch op {
+	case Greater:
+		return v > threshold
+	case GreaterEqual:
+		return v >= threshold
+	case Less:
+		return v < threshold
+	case LessEqual:
+		return v <= threshold
+	case Equal:
+		return v == threshold
+	case NotEqual:
+		return v != threshold
+	}
+	return false
+}
+
+// Statistics a rule can compare besides percentiles, written e.g. "p99" or
+// "p99.9".
+const (
+	// StatValue is the value of a counter or gauge.  It is the statistic of
+	// rules that do not name one.
+	StatValue = "value"
+	// StatRate is the per-second increase of a counter or number of
+	// updates of a histogram since the previous evaluation.  Gauges have no
+	// rate: snapshots reset some to their change since the previous one
+	// while others, such as functional gauges, hold levels.
+	StatRate = "rate"
+	// StatCount is the number of updates of a histogram.
+	StatCount = "count"
+	StatMin   = "min"
+	StatMax   = "max"
+	StatMean  = "mean"
+	StatSum   = "sum"
+)
+
+// Rule is a threshold on a statistic of a metric that must hold for a
+// duration before its alert fires.
+type Rule struct {
+	// Name names the alert, the expression by default.
+	Name string
+	// Expr is the expression the rule was parsed from.
+	Expr      string
+	Metric    string
+	Stat      string
+	Op        Op
+	Threshold float64
+	For       time.Duration
+}
+
+// ParseRule parses an expression of the form
+//
+//	<metric> [<stat>] <op> <threshold> [for <duration>]
+//
+// e.g. "http.latency p99 > 250ms for 2m" or "errors rate > 5/s".  The
+// statistic is one of the Stat constants or a percentile such as "p99".
+// The threshold is a number, a duration, which is converted to nanoseconds
+// as histograms of latency record them, or a rate such as "5/s" or "300/m",
+// which is converted to a per-second rate.
+func ParseRule(expr string) (Rule, error) {
+	fields := strings.Fields(expr)
+	rule := Rule{Expr: expr, Stat: StatValue}
+	if len(fields) < 3 {
+		return rule, fmt.Errorf("alert: %q: expected <metric> [<stat>] <op> <threshold> [for <duration>]", expr)
+	}
+	rule.Metric = fields[0]
+	rest := fields[1:]
+	if !isOp(rest[0]) {
+		rule.Stat = rest[0]
+		rest = rest[1:]
+	}
+	if err := validStat(rule.Stat); err != nil {
+		return rule, fmt.Errorf("alert: %q: %w", expr, err)
+	}
+	if len(rest) < 2 || !isOp(rest[0]) {
+		return rule, fmt.Errorf("alert: %q: expected an operator", expr)
+	}
+	rule.Op = Op(rest[0])
+	threshold, err := parseThreshold(rest[1])
+	if err != nil {
+		return rule, fmt.Errorf("alert: %q: %w", expr, err)
+	}
+	rule.Threshold = threshold
+	rest = rest[2:]
+	switch {
+	case len(rest) == 0:
+	case len(rest) == 2 && rest[0] == "for":
+		rule.For, err = time.ParseDuration(rest[1])
+		if err != nil || rule.For < 0 {
+			return rule, fmt.Errorf("alert: %q: invalid duration %q", expr, rest[1])
+		}
+	default:
+		return rule, fmt.Errorf("alert: %q: unexpected %q", expr, strings.Join(rest, " "))
+	}
+	return rule, nil
+}
+
+// MustParseRule is like ParseRule but panics if the expression is invalid.
+func MustParseRule(expr string) Rule {
+	rule, err := ParseRule(expr)
+	if err != nil {
+		panic(err)
+	}
+	return rule
+}
+
+func (r Rule) name() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	if r.Expr != "" {
+		return r.Expr
+	}
+	return fmt.Sprintf("%s %s %s %v", r.Metric, r.Stat, r.Op, r.Threshold)
+}
+
+func isOp(s string) bool {
+	switch Op(s) {
+	case Greater, GreaterEqual, Less, LessEqual, Equal, NotEqual:
+		return true
+	}
+	return false
+}
+
+func validStat(stat string) error {
+	switch stat {
+	case StatValue, StatRate, StatCount, StatMin, StatMax, StatMean, StatSum:
+		return nil
+	}
+	if _, ok := percentile(stat); ok {
+		return nil
+	}
+	return fmt.Errorf("unknown statistic %q", stat)
+}
+
+// percentile parses a statistic such as "p99.9" into 0.999.
+func percentile(stat string) (float64, bool) {
+	if !strings.HasPrefix(stat, "p") {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(stat[1:], 64)
+	if err != nil || p < 0 || p > 100 {
+		return 0, false
+	}
+	return p / 100, true
+}
+
+func parseThreshold(s string) (float64, error) {
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v, nil
+	}
+	if n, unit, ok := strings.Cut(s, "/"); ok {
+		v, err := strconv.ParseFloat(n, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid rate %q", s)
+		}
+		per, err := time.ParseDuration("1" + unit)
+		if err != nil {
+			return 0, fmt.Errorf("invalid rate %q", s)
+		}
+		return v / per.Seconds(), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return float64(d), nil
+	}
+	return 0, fmt.Errorf("invalid threshold %q", s)
+}
+
+// value returns the statistic of the rule's metric in s, or false if s has
+// no such metric or the statistic does not apply to it.  prev is the
+// previous snapshot, taken elapsed earlier, or nil.
+func (r Rule) value(s, prev *reporter.RegistrySnapshot, elapsed time.Duration) (float64, bool) {
+	if v, ok := s.Counters[r.Metric]; ok {
+		switch r.Stat {
+		case StatValue:
+			return float64(v), true
+		case StatRate:
+			if prev == nil || elapsed <= 0 {
+				return 0, false
+			}
+			p, ok := prev.Counters[r.Metric]
+			if !ok {
+				return 0, false
+			}
+			return float64(v-p) / elapsed.Seconds(), true
+		}
+		return 0, false
+	}
+	if v, ok := s.Gauges[r.Metric]; ok {
+		return float64(v), r.Stat == StatValue
+	}
+	if v, ok := s.GaugeFloat64s[r.Metric]; ok {
+		return v, r.Stat == StatValue
+	}
+	if h, ok := s.Histograms[r.Metric]; ok {
+		return histogramValue(r.Stat, h.ReqCount(), elapsed, func() (float64, float64, float64, float64) {
+			return float64(h.ExactMin()), float64(h.ExactMax()), h.ExactMean(), float64(h.ExactSum())
+		}, h.Percentile)
+	}
+	if h, ok := s.Float64Histograms[r.Metric]; ok {
+		return histogramValue(r.Stat, h.ReqCount(), elapsed, func() (float64, float64, float64, float64) {
+			return h.ExactMin(), h.ExactMax(), h.ExactMean(), h.ExactSum()
+		}, h.Percentile)
+	}
+	return 0, false
+}
+
+// histogramValue computes a statistic of a histogram snapshot.  Statistics
+// of the values of an empty histogram are undefined.
+func histogramValue(stat string, count int64, elapsed time.Duration, stats func() (min, max, mean, sum float64), quantile func(float64) float64) (float64, bool) {
+	switch stat {
+	case StatCount:
+		return float64(count), true
+	case StatRate:
+		if elapsed <= 0 {
+			return 0, false
+		}
+		return float64(count) / elapsed.Seconds(), true
+	}
+	if count == 0 {
+		return 0, false
+	}
+	min, max, mean, sum := stats()
+	switch stat {
+	case StatMin:
+		return min, true
+	case StatMax:
+		return max, true
+	case StatMean:
+		return mean, true
+	case StatSum:
+		return sum, true
+	}
+	if p, ok := percentile(stat); ok {
+		return quantile(p), true
+	}
+	return 0, false
+}
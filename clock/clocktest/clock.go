@@ -0,0 +1,142 @@
+// Package clocktest provides a manually advanced clock.Clock for tests.
+package clocktest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/someview/go-metrics/clock"
+)
+
+// Clock is a clock.Clock whose time only moves when Add or Set is called.
+// Timers and tickers fire synchronously as the time passes their deadline;
+// like their time package counterparts, ticks are dropped if the previous
+// one has not been received.
+type Clock struct {
+	mutex   sync.Mutex
+	cond    sync.Cond
+	now     time.Time
+	waiters []*waiter
+}
+
+type waiter struct {
+	at     time.Time
+	period time.Duration // zero for one-shot timers
+	ch     chan time.Time
+}
+
+// NewClock constructs a manual clock starting at now.
+func NewClock(now time.Time) *Clock {
+	c := &Clock{now: now}
+	c.cond.L = &c.mutex
+	return c
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the clock's time once it has been
+// advanced by d.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	w := &waiter{at: c.now.Add(d), ch: make(chan time.Time, 1)}
+	c.schedule(w)
+	return w.ch
+}
+
+// NewTicker returns a ticker that ticks every time the clock passes another
+// multiple of d.
+func (c *Clock) NewTicker(d time.Duration) clock.Ticker {
+	if d <= 0 {
+		panic("clocktest: non-positive interval for NewTicker")
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	w := &waiter{at: c.now.Add(d), period: d, ch: make(chan time.Time, 1)}
+	c.schedule(w)
+	return &ticker{c: c, w: w}
+}
+
+// Add advances the clock by d, firing timers and tickers in deadline order.
+func (c *Clock) Add(d time.Duration) {
+	c.mutex.Lock()
+	target := c.now.Add(d)
+	c.mutex.Unlock()
+	c.Set(target)
+}
+
+// Set moves the clock to t, firing timers and tickers in deadline order.  The
+// clock never moves backwards.
+func (c *Clock) Set(t time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for {
+		next := -1
+		for i, w := range c.waiters {
+			if !w.at.After(t) && (next < 0 || w.at.Before(c.waiters[next].at)) {
+				next = i
+			}
+		}
+		if next < 0 {
+			break
+		}
+		w := c.waiters[next]
+		c.now = w.at
+		select {
+		case w.ch <- w.at:
+		default:
+		}
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			c.remove(w)
+		}
+	}
+	if t.After(c.now) {
+		c.now = t
+	}
+}
+
+// BlockUntil blocks until at least n timers and tickers are pending.  It lets
+// a test wait for a goroutine to start waiting on the clock before advancing
+// it.
+func (c *Clock) BlockUntil(n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+func (c *Clock) schedule(w *waiter) {
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+}
+
+func (c *Clock) remove(w *waiter) {
+	for i, o := range c.waiters {
+		if o == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			break
+		}
+	}
+	c.cond.Broadcast()
+}
+
+type ticker struct {
+	c *Clock
+	w *waiter
+}
+
+func (t *ticker) C() <-chan time.Time { return t.w.ch }
+
+func (t *ticker) Stop() {
+	t.c.mutex.Lock()
+	defer t.c.mutex.Unlock()
+	t.c.remove(t.w)
+}
@@ -0,0 +1,53 @@
+package clocktest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClock_After(t *testing.T) {
+	start := time.Unix(0, 0)
+	c := NewClock(start)
+	ch := c.After(time.Second)
+	c.Add(999 * time.Millisecond)
+	select {
+	case <-ch:
+		t.Fatal("timer fired early")
+	default:
+	}
+	c.Add(time.Millisecond)
+	assert.Equal(t, start.Add(time.Second), <-ch)
+	assert.Equal(t, start.Add(time.Second), c.Now())
+}
+
+func TestClock_Ticker(t *testing.T) {
+	start := time.Unix(0, 0)
+	c := NewClock(start)
+	ticker := c.NewTicker(time.Minute)
+	c.Add(time.Minute)
+	assert.Equal(t, start.Add(time.Minute), <-ticker.C())
+	// Ticks that are not received are dropped, as with time.Ticker.
+	c.Add(3 * time.Minute)
+	assert.Equal(t, start.Add(2*time.Minute), <-ticker.C())
+	ticker.Stop()
+	c.Add(time.Minute)
+	select {
+	case <-ticker.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+}
+
+func TestClock_BlockUntil(t *testing.T) {
+	c := NewClock(time.Unix(0, 0))
+	done := make(chan struct{})
+	go func() {
+		<-c.After(time.Second)
+		close(done)
+	}()
+	c.BlockUntil(1)
+	c.Add(time.Second)
+	<-done
+}
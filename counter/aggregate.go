@@ -0,0 +1,12 @@
+package counter
+
+// Sum aggregates counter snapshots taken from several registries, e.g. the
+// same counter on several replicas.  Counters count disjoint events, so the
+// fleet-wide value is exactly their sum.
+func Sum(snapshots ...int64) int64 {
+	var sum int64
+	for _, v := range snapshots {
+		sum += v
+	}
+	return sum
+}
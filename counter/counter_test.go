@@ -1,7 +1,10 @@
 package counter
 
 import (
+	"sync"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func BenchmarkCounter(b *testing.B) {
@@ -11,3 +14,67 @@ func BenchmarkCounter(b *testing.B) {
 		c.Inc(1)
 	}
 }
+
+func BenchmarkStripedCounter(b *testing.B) {
+	c := NewStripedCounter()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Inc(1)
+	}
+}
+
+// Run the parallel benchmarks with -cpu 1,2,4,8,... to compare how the
+// counters scale.
+func BenchmarkCounterParallel(b *testing.B) {
+	benchmarkCounterParallel(b, NewCounter())
+}
+
+func BenchmarkStripedCounterParallel(b *testing.B) {
+	benchmarkCounterParallel(b, NewStripedCounter())
+}
+
+func benchmarkCounterParallel(b *testing.B, c Counter) {
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Inc(1)
+		}
+	})
+}
+
+func TestStripedCounter(t *testing.T) {
+	c := NewStripedCounter()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				c.Inc(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(8000), c.Snapshot())
+	assert.Equal(t, int64(8000), c.Swap(5))
+	assert.Equal(t, int64(5), c.Snapshot())
+	c.Inc(-2)
+	assert.Equal(t, int64(3), c.SnapshotAndReset())
+	assert.Equal(t, int64(0), c.Snapshot())
+}
+
+func TestSum(t *testing.T) {
+	assert.Equal(t, int64(6), Sum(1, 2, 3))
+	assert.Equal(t, int64(0), Sum())
+}
+
+func TestFunctionalCounter(t *testing.T) {
+	var v int64 = 3
+	c := NewFunctionalCounter(func() int64 { return v })
+	assert.Equal(t, int64(3), c.SnapshotAndReset())
+	v = 5
+	assert.Equal(t, int64(5), c.Snapshot())
+	c.Inc(1)
+	assert.Equal(t, int64(5), c.Swap(0))
+	assert.Equal(t, int64(5), c.Snapshot())
+}
@@ -0,0 +1,28 @@
+package counter
+
+// FunctionalCounter is a Counter whose value is computed by a function each
+// time it is read, e.g. to expose a total kept by the operating system.
+// Reading it never resets anything.
+type FunctionalCounter struct {
+	value func() int64
+}
+
+// NewFunctionalCounter constructs a new FunctionalCounter.
+func NewFunctionalCounter(f func() int64) Counter {
+	return &FunctionalCounter{value: f}
+}
+
+// Inc does nothing: the value of a FunctionalCounter is owned by its
+// function.
+func (c *FunctionalCounter) Inc(int64) {}
+
+// Swap returns the current value of the function and changes nothing, as the
+// value is owned by the function.
+func (c *FunctionalCounter) Swap(int64) int64 { return c.value() }
+
+// Snapshot returns the current value of the function.
+func (c *FunctionalCounter) Snapshot() int64 { return c.value() }
+
+// SnapshotAndReset returns the current value of the function, as there is
+// nothing to reset.
+func (c *FunctionalCounter) SnapshotAndReset() int64 { return c.value() }
@@ -0,0 +1,275 @@
+// Package dashboard serves an HTML page listing the metrics of a
+// reporter.Registry with their current values and, given a history.Store,
+// sparklines of their recent history.
+package dashboard
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"io/fs"
+	"math"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/someview/go-metrics/clock"
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/health"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/history"
+	"github.com/someview/go-metrics/reporter"
+)
+
+//go:embed assets
+var assets embed.FS
+
+var page = template.Must(template.ParseFS(assets, "assets/index.html"))
+
+// Sparkline dimensions, in SVG user units.
+const (
+	sparklineWidth  = 120
+	sparklineHeight = 24
+)
+
+// Handler serves the dashboard.  Mount it at a path ending with a slash,
+// e.g. "/debug/metrics/ui/", as it serves its stylesheet below it.  The
+// "prefix" query parameter filters metrics by name prefix.
+//
+// Values are read without resetting anything, so the page can be viewed
+// while reporters run.
+type Handler struct {
+	registry reporter.Registry
+	history  *history.Store
+	window   time.Duration
+	refresh  time.Duration
+	clock    clock.Clock
+	static   http.Handler
+	page     *template.Template
+}
+
+// Option configures a Handler.
+type Option func(*Handler)
+
+// WithHistory sets the store sparklines are drawn from.  Without one the
+// page has no sparklines.
+func WithHistory(s *history.Store) Option {
+	return func(h *Handler) {
+		h.history = s
+	}
+}
+
+// WithWindow sets the span of history sparklines cover, 15 minutes by
+// default.
+func WithWindow(d time.Duration) Option {
+	return func(h *Handler) {
+		h.window = d
+	}
+}
+
+// WithRefresh sets how often the page reloads itself, every 5 seconds by
+// default.  A non-positive interval disables reloading.
+func WithRefresh(d time.Duration) Option {
+	return func(h *Handler) {
+		h.refresh = d
+	}
+}
+
+// WithClock sets the clock that ends the span of sparklines.
+func WithClock(c clock.Clock) Option {
+	return func(h *Handler) {
+		h.clock = c
+	}
+}
+
+// NewHandler constructs a Handler for the metrics of r.
+func NewHandler(r reporter.Registry, opts ...Option) *Handler {
+	static, _ := fs.Sub(assets, "assets/static")
+	h := &Handler{
+		registry: r,
+		window:   15 * time.Minute,
+		refresh:  5 * time.Second,
+		clock:    clock.System(),
+		static:   http.FileServer(http.FS(static)),
+		page:     page,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// row is a metric as shown on the page.
+type row struct {
+	Name      string
+	Kind      string
+	Fields    []field
+	Sparkline string
+	Unhealthy bool
+}
+
+type field struct {
+	Name, Value string
+}
+
+type pageData struct {
+	Prefix  string
+	Refresh int
+	Rows    []row
+	Total   int
+	Now     string
+}
+
+// ServeHTTP serves the page, or a static asset below it.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if dir, file := path.Split(r.URL.Path); strings.HasSuffix(dir, "/static/") {
+		if file == "" {
+			http.NotFound(w, r)
+			return
+		}
+		http.StripPrefix(dir, h.static).ServeHTTP(w, r)
+		return
+	}
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		target := r.URL.Path + "/"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+		return
+	}
+
+	prefix := r.URL.Query().Get("prefix")
+	data := pageData{Prefix: prefix, Refresh: int(h.refresh / time.Second)}
+	now := h.clock.Now()
+	data.Now = now.Format(time.RFC3339)
+	h.registry.Each(func(name string, i interface{}) {
+		data.Total++
+		if !strings.HasPrefix(name, prefix) {
+			return
+		}
+		if row, ok := h.row(name, i, now); ok {
+			data.Rows = append(data.Rows, row)
+		}
+	})
+	sort.Slice(data.Rows, func(i, j int) bool { return data.Rows[i].Name < data.Rows[j].Name })
+
+	h.render(w, data)
+}
+
+// render writes the page, or a 500 response if it cannot be rendered, which
+// writing straight to w would leave as a truncated page.
+func (h *Handler) render(w http.ResponseWriter, data pageData) {
+	var buf bytes.Buffer
+	if err := h.page.Execute(&buf, data); err != nil {
+		http.Error(w, "dashboard: cannot render page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(buf.Bytes())
+}
+
+// row reads the metric without resetting it.
+func (h *Handler) row(name string, i interface{}, now time.Time) (row, bool) {
+	res := row{Name: name}
+	key := history.Key{Metric: name, Field: "value"}
+	switch metric := i.(type) {
+	case counter.Counter:
+		res.Kind = "counter"
+		res.Fields = []field{{"count", strconv.FormatInt(metric.Snapshot(), 10)}}
+		key.Field = "count"
+	case guage.Gauge:
+		res.Kind = "gauge"
+		res.Fields = []field{{"value", strconv.FormatInt(metric.Snapshot(), 10)}}
+	case guage.GaugeFloat64:
+		res.Kind = "gauge"
+		res.Fields = []field{{"value", formatFloat(metric.Snapshot())}}
+	case histogram.Histogram:
+		s := metric.Sample().Snapshot()
+		ps := s.Percentiles([]float64{0.5, 0.99})
+		res.Kind = "histogram"
+		res.Fields = []field{
+			{"count", strconv.FormatInt(s.ReqCount(), 10)},
+			{"min", strconv.FormatInt(s.Min(), 10)},
+			{"mean", formatFloat(s.Mean())},
+			{"max", strconv.FormatInt(s.Max(), 10)},
+			{"p50", formatFloat(ps[0])},
+			{"p99", formatFloat(ps[1])},
+		}
+		key.Field = "99%"
+	case histogram.Float64Histogram:
+		s := metric.Sample().Snapshot()
+		ps := s.Percentiles([]float64{0.5, 0.99})
+		res.Kind = "histogram"
+		res.Fields = []field{
+			{"count", strconv.FormatInt(s.ReqCount(), 10)},
+			{"min", formatFloat(s.Min())},
+			{"mean", formatFloat(s.Mean())},
+			{"max", formatFloat(s.Max())},
+			{"p50", formatFloat(ps[0])},
+			{"p99", formatFloat(ps[1])},
+		}
+		key.Field = "99%"
+	case health.Healthcheck:
+		status := metric.Status()
+		res.Kind = "healthcheck"
+		res.Unhealthy = !status.Healthy()
+		state := "healthy"
+		if status.Err != nil {
+			state = status.Err.Error()
+		}
+		res.Fields = []field{
+			{"status", state},
+			{"failures", strconv.FormatInt(status.ConsecutiveFailures, 10)},
+		}
+		key.Metric = reporter.HealthyGaugeName(name)
+	default:
+		return res, false
+	}
+	if h.history != nil {
+		res.Sparkline = sparkline(h.history.Range(key, now.Add(-h.window), now))
+	}
+	return res, true
+}
+
+// sparkline returns the SVG polyline points plotting the values of points,
+// scaled to the sparkline's box.
+func sparkline(points []history.Point) string {
+	if len(points) < 2 {
+		return ""
+	}
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, p := range points {
+		min = math.Min(min, p.Value)
+		max = math.Max(max, p.Value)
+	}
+	start, end := points[0].Time, points[len(points)-1].Time
+	span := end.Sub(start).Seconds()
+	var b strings.Builder
+	for i, p := range points {
+		x := 0.0
+		if span > 0 {
+			x = p.Time.Sub(start).Seconds() / span * sparklineWidth
+		}
+		y := sparklineHeight / 2.0
+		if max > min {
+			y = (max - p.Value) / (max - min) * sparklineHeight
+		}
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strconv.FormatFloat(x, 'f', 1, 64))
+		b.WriteByte(',')
+		b.WriteString(strconv.FormatFloat(y, 'f', 1, 64))
+	}
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
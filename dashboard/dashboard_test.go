@@ -0,0 +1,118 @@
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/someview/go-metrics/clock/clocktest"
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/health"
+	"github.com/someview/go-metrics/history"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/someview/go-metrics/sample"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(t *testing.T, h http.Handler, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestHandler(t *testing.T) {
+	epoch := time.Unix(0, 0)
+	c := clocktest.NewClock(epoch.Add(time.Minute))
+	r := reporter.NewRegistry()
+	reporter.GetOrRegisterCounter("http.requests", r).Inc(42)
+	reporter.GetOrRegisterGauge("http.inflight", r).Inc(3)
+	reporter.GetOrRegisterHistogram("http.latency", r, sample.NewSlidingWindowSample(10)).Update(250)
+	reporter.GetOrRegisterGaugeFloat64("load", r).Update(0.5)
+	db := health.NewHealthcheck(func(context.Context) error { return errors.New("refused") })
+	db.Check(context.Background())
+	require.NoError(t, r.Register("db", db))
+
+	store := history.NewStore()
+	for i := int64(0); i < 3; i++ {
+		s := reporter.NewRegistrySnapshot()
+		s.Counters["http.requests"] = i * 10
+		store.Record(epoch.Add(time.Duration(i)*20*time.Second), s)
+	}
+	h := NewHandler(r, WithHistory(store), WithClock(c), WithRefresh(10*time.Second))
+
+	rec := serve(t, h, "/debug/metrics/ui/")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+	assert.Contains(t, body, `<meta http-equiv="refresh" content="10">`)
+	assert.Contains(t, body, "5 of 5 metrics")
+	assert.Contains(t, body, `<span class="key">count</span> 42`)
+	assert.Contains(t, body, `<span class="key">value</span> 3`)
+	assert.Contains(t, body, `<span class="key">p99</span> 250`)
+	assert.Contains(t, body, `<span class="key">value</span> 0.5`)
+	assert.Contains(t, body, `<tr class="healthcheck unhealthy">`)
+	assert.Contains(t, body, `<span class="key">status</span> refused`)
+	assert.Contains(t, body, `<polyline points="0.0,24.0 60.0,12.0 120.0,0.0"/>`)
+	assert.Equal(t, 1, strings.Count(body, "<polyline"), "only metrics with history have sparklines")
+	assert.Less(t, strings.Index(body, "db"), strings.Index(body, "http.inflight"), "rows are sorted")
+
+	assert.Equal(t, int64(3), r.Get("http.inflight").(guage.Gauge).Snapshot(), "gauges are not reset")
+	assert.Equal(t, int64(1), reporter.GetOrRegisterHistogram("http.latency", r, nil).Sample().Snapshot().ReqCount(),
+		"histograms are not reset")
+}
+
+func TestHandler_Prefix(t *testing.T) {
+	r := reporter.NewRegistry()
+	reporter.GetOrRegisterCounter("http.requests", r)
+	reporter.GetOrRegisterCounter("sql.exec.errors", r)
+	body := serve(t, NewHandler(r, WithRefresh(0)), "/debug/metrics/ui/?prefix=sql.").Body.String()
+	assert.Contains(t, body, "sql.exec.errors")
+	assert.NotContains(t, body, "http.requests")
+	assert.Contains(t, body, `value="sql."`)
+	assert.Contains(t, body, "1 of 2 metrics")
+	assert.NotContains(t, body, "http-equiv")
+
+	body = serve(t, NewHandler(r), "/debug/metrics/ui/?prefix=%3Cscript%3E").Body.String()
+	assert.Contains(t, body, "no metrics starting with “&lt;script&gt;”")
+	assert.NotContains(t, body, "<script>")
+}
+
+func TestHandler_Static(t *testing.T) {
+	h := NewHandler(reporter.NewRegistry())
+	rec := serve(t, h, "/debug/metrics/ui/static/style.css")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/css")
+	assert.Contains(t, rec.Body.String(), "polyline")
+
+	assert.Equal(t, http.StatusNotFound, serve(t, h, "/debug/metrics/ui/static/").Code)
+	assert.Equal(t, http.StatusNotFound, serve(t, h, "/debug/metrics/ui/static/missing.js").Code)
+
+	rec = serve(t, h, "/debug/metrics/ui?prefix=http")
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "/debug/metrics/ui/?prefix=http", rec.Header().Get("Location"))
+}
+
+func TestHandler_RenderError(t *testing.T) {
+	h := NewHandler(reporter.NewRegistry())
+	h.page = template.Must(template.New("page").Parse(`<p>{{.Missing}}</p>`))
+	rec := serve(t, h, "/")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "<p>")
+}
+
+func TestSparkline(t *testing.T) {
+	epoch := time.Unix(0, 0)
+	assert.Equal(t, "", sparkline(nil))
+	assert.Equal(t, "", sparkline([]history.Point{{Time: epoch, Value: 1}}))
+	assert.Equal(t, "0.0,12.0 120.0,12.0", sparkline([]history.Point{
+		{Time: epoch, Value: 1},
+		{Time: epoch.Add(time.Second), Value: 1},
+	}), "flat series are centred")
+}
@@ -0,0 +1,94 @@
+package grpcmetrics
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/someview/go-metrics/reporter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ClientMetrics records the RPCs made by a gRPC client.  A streaming RPC is
+// handled when receiving from it ends: with codes.OK at io.EOF or at the
+// response of a client-streaming RPC, or with the code of the error
+// otherwise.  Streams that are abandoned without reading
+// them to their end are never counted as handled.
+type ClientMetrics struct {
+	metrics *metrics
+}
+
+// NewClientMetrics constructs client metrics kept in r.
+func NewClientMetrics(r reporter.Registry, opts ...Option) *ClientMetrics {
+	return &ClientMetrics{metrics: newMetrics(r, "grpc.client", opts)}
+}
+
+// UnaryClientInterceptor returns an interceptor recording unary RPCs, each
+// counted as one message sent and, unless it fails, one message received.
+func (c *ClientMetrics) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		m := c.metrics.method(method)
+		start := c.metrics.clock.Now()
+		m.started.Inc(1)
+		m.msgSent.Inc(1)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil {
+			m.msgReceived.Inc(1)
+		}
+		m.done(start, status.Code(err))
+		return err
+	}
+}
+
+// StreamClientInterceptor returns an interceptor recording streaming RPCs
+// and the messages they send and receive.
+func (c *ClientMetrics) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		m := c.metrics.method(method)
+		start := c.metrics.clock.Now()
+		m.started.Inc(1)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			m.done(start, status.Code(err))
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, method: m, start: start, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+// clientStream counts the messages of a client stream and records its end.
+// Streams whose server sends a single message end with that message.
+type clientStream struct {
+	grpc.ClientStream
+	method        *methodMetrics
+	start         time.Time
+	serverStreams bool
+	once          sync.Once
+}
+
+func (s *clientStream) SendMsg(msg any) error {
+	err := s.ClientStream.SendMsg(msg)
+	if err == nil {
+		s.method.msgSent.Inc(1)
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(msg any) error {
+	err := s.ClientStream.RecvMsg(msg)
+	switch err {
+	case nil:
+		s.method.msgReceived.Inc(1)
+		if !s.serverStreams {
+			s.once.Do(func() { s.method.done(s.start, codes.OK) })
+		}
+	case io.EOF:
+		s.once.Do(func() { s.method.done(s.start, codes.OK) })
+	default:
+		s.once.Do(func() { s.method.done(s.start, status.Code(err)) })
+	}
+	return err
+}
@@ -0,0 +1,20 @@
+module github.com/someview/go-metrics/grpcmetrics
+
+go 1.23.0
+
+require (
+	github.com/someview/go-metrics v0.0.0-20261019093610-aa635efda6da
+	github.com/stretchr/testify v1.9.0
+	google.golang.org/grpc v1.75.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/net v0.41.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.26.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250707201910-8d1bb00bc6a7 // indirect
+	google.golang.org/protobuf v1.36.6 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,140 @@
+// Package grpcmetrics provides gRPC server and client interceptors that keep
+// metrics in a reporter.Registry.  It is a module of its own, so that
+// programs using the other packages do not depend on gRPC, and uses only the
+// exported API of github.com/someview/go-metrics.
+package grpcmetrics
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/someview/go-metrics/clock"
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/someview/go-metrics/sample"
+	"google.golang.org/grpc/codes"
+)
+
+// Option configures ServerMetrics or ClientMetrics.
+type Option func(*metrics)
+
+// WithPrefix sets the prefix of metric names, "grpc.server" for ServerMetrics
+// and "grpc.client" for ClientMetrics by default.
+func WithPrefix(prefix string) Option {
+	return func(m *metrics) {
+		m.prefix = prefix
+	}
+}
+
+// WithSample sets the constructor of the samples underlying the handling
+// time histograms, sample.NewDefaultSample by default.
+func WithSample(f func() sample.Sample) Option {
+	return func(m *metrics) {
+		m.newSample = f
+	}
+}
+
+// WithClock sets the clock used to measure handling time.
+func WithClock(c clock.Clock) Option {
+	return func(m *metrics) {
+		m.clock = c
+	}
+}
+
+// metrics records RPCs.  Metrics are named by joining the prefix, the metric,
+// the service and the method with dots, e.g.
+//
+//	grpc.server.started.pkg.Service.Method        counter
+//	grpc.server.handled.pkg.Service.Method.OK     counter, by status code
+//	grpc.server.handling.pkg.Service.Method       histogram of nanoseconds
+//	grpc.server.msg.received.pkg.Service.Method   counter
+//	grpc.server.msg.sent.pkg.Service.Method       counter
+type metrics struct {
+	registry  reporter.Registry
+	prefix    string
+	newSample func() sample.Sample
+	clock     clock.Clock
+	methods   sync.Map // full method -> *methodMetrics
+}
+
+type methodMetrics struct {
+	metrics      *metrics
+	name         string
+	started      counter.Counter
+	handling     histogram.Histogram
+	msgReceived  counter.Counter
+	msgSent      counter.Counter
+	handledMutex sync.Mutex
+	handled      map[codes.Code]counter.Counter
+}
+
+func newMetrics(r reporter.Registry, prefix string, opts []Option) *metrics {
+	m := &metrics{
+		registry:  r,
+		prefix:    prefix,
+		newSample: sample.NewDefaultSample,
+		clock:     clock.System(),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (m *metrics) metricName(parts ...string) string {
+	if m.prefix != "" {
+		parts = append([]string{m.prefix}, parts...)
+	}
+	return strings.Join(parts, ".")
+}
+
+func (m *metrics) histogram(name string) histogram.Histogram {
+	return m.registry.GetOrRegister(name, func() histogram.Histogram {
+		return histogram.NewHistogram(m.newSample())
+	}).(histogram.Histogram)
+}
+
+// method returns the metrics of a method named "/service/method".
+func (m *metrics) method(fullMethod string) *methodMetrics {
+	if mm, ok := m.methods.Load(fullMethod); ok {
+		return mm.(*methodMetrics)
+	}
+	service, method := SplitMethodName(fullMethod)
+	name := service + "." + method
+	mm := &methodMetrics{
+		metrics:     m,
+		name:        name,
+		started:     reporter.GetOrRegisterCounter(m.metricName("started", name), m.registry),
+		handling:    m.histogram(m.metricName("handling", name)),
+		msgReceived: reporter.GetOrRegisterCounter(m.metricName("msg.received", name), m.registry),
+		msgSent:     reporter.GetOrRegisterCounter(m.metricName("msg.sent", name), m.registry),
+		handled:     make(map[codes.Code]counter.Counter),
+	}
+	actual, _ := m.methods.LoadOrStore(fullMethod, mm)
+	return actual.(*methodMetrics)
+}
+
+// done records the end of an RPC started at start.
+func (mm *methodMetrics) done(start time.Time, code codes.Code) {
+	mm.handling.Update(int64(mm.metrics.clock.Now().Sub(start)))
+	mm.handledMutex.Lock()
+	c, ok := mm.handled[code]
+	if !ok {
+		c = reporter.GetOrRegisterCounter(mm.metrics.metricName("handled", mm.name, code.String()), mm.metrics.registry)
+		mm.handled[code] = c
+	}
+	mm.handledMutex.Unlock()
+	c.Inc(1)
+}
+
+// SplitMethodName splits a full method name such as "/pkg.Service/Method"
+// into its service and method.
+func SplitMethodName(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndexByte(fullMethod, '/'); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
@@ -0,0 +1,119 @@
+package grpcmetrics
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const healthService = "grpc.health.v1.Health"
+
+// startHealthServer serves the standard health service over an in-process
+// connection, recording server metrics in server and client metrics in
+// client.
+func startHealthServer(t *testing.T, server, client reporter.Registry) healthpb.HealthClient {
+	lis := bufconn.Listen(1 << 20)
+	sm := NewServerMetrics(server)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(sm.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(sm.StreamServerInterceptor()),
+	)
+	h := health.NewServer()
+	h.SetServingStatus("up", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, h)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	cm := NewClientMetrics(client)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(cm.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(cm.StreamClientInterceptor()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func counterValue(r reporter.Registry, name string) int64 {
+	if c, ok := r.Get(name).(counter.Counter); ok {
+		return c.Snapshot()
+	}
+	return -1
+}
+
+func TestUnary(t *testing.T) {
+	server, client := reporter.NewRegistry(), reporter.NewRegistry()
+	hc := startHealthServer(t, server, client)
+
+	_, err := hc.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "up"})
+	require.NoError(t, err)
+	_, err = hc.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	for prefix, r := range map[string]reporter.Registry{"grpc.server": server, "grpc.client": client} {
+		name := healthService + ".Check"
+		assert.Equal(t, int64(2), counterValue(r, prefix+".started."+name), prefix)
+		assert.Equal(t, int64(1), counterValue(r, prefix+".handled."+name+".OK"), prefix)
+		assert.Equal(t, int64(1), counterValue(r, prefix+".handled."+name+".NotFound"), prefix)
+		assert.Equal(t, int64(2), r.Get(prefix+".handling."+name).(histogram.Histogram).Sample().Snapshot().Count(), prefix)
+	}
+	assert.Equal(t, int64(2), counterValue(server, "grpc.server.msg.received."+healthService+".Check"))
+	assert.Equal(t, int64(1), counterValue(server, "grpc.server.msg.sent."+healthService+".Check"))
+	assert.Equal(t, int64(2), counterValue(client, "grpc.client.msg.sent."+healthService+".Check"))
+	assert.Equal(t, int64(1), counterValue(client, "grpc.client.msg.received."+healthService+".Check"))
+}
+
+func TestStream(t *testing.T) {
+	server, client := reporter.NewRegistry(), reporter.NewRegistry()
+	hc := startHealthServer(t, server, client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := hc.Watch(ctx, &healthpb.HealthCheckRequest{Service: "up"})
+	require.NoError(t, err)
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	cancel()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Canceled, status.Code(err))
+
+	name := healthService + ".Watch"
+	assert.Equal(t, int64(1), counterValue(client, "grpc.client.started."+name))
+	assert.Equal(t, int64(1), counterValue(client, "grpc.client.msg.sent."+name))
+	assert.Equal(t, int64(1), counterValue(client, "grpc.client.msg.received."+name))
+	assert.Equal(t, int64(1), counterValue(client, "grpc.client.handled."+name+".Canceled"))
+
+	assert.Equal(t, int64(1), counterValue(server, "grpc.server.started."+name))
+	assert.Eventually(t, func() bool {
+		return counterValue(server, "grpc.server.handled."+name+".Canceled") == 1
+	}, 5*time.Second, time.Millisecond)
+	assert.Equal(t, int64(1), counterValue(server, "grpc.server.msg.received."+name))
+	assert.Equal(t, int64(1), counterValue(server, "grpc.server.msg.sent."+name))
+}
+
+func TestSplitMethodName(t *testing.T) {
+	service, method := SplitMethodName("/grpc.health.v1.Health/Check")
+	assert.Equal(t, "grpc.health.v1.Health", service)
+	assert.Equal(t, "Check", method)
+	service, method = SplitMethodName("Check")
+	assert.Equal(t, "unknown", service)
+	assert.Equal(t, "Check", method)
+}
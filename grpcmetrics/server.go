@@ -0,0 +1,73 @@
+package grpcmetrics
+
+import (
+	"context"
+
+	"github.com/someview/go-metrics/reporter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// ServerMetrics records the RPCs handled by a gRPC server.  An RPC is
+// started when the interceptor is called and handled when its handler
+// returns, with the status code of the returned error.
+type ServerMetrics struct {
+	metrics *metrics
+}
+
+// NewServerMetrics constructs server metrics kept in r.
+func NewServerMetrics(r reporter.Registry, opts ...Option) *ServerMetrics {
+	return &ServerMetrics{metrics: newMetrics(r, "grpc.server", opts)}
+}
+
+// UnaryServerInterceptor returns an interceptor recording unary RPCs, each
+// counted as one message received and, unless it fails, one message sent.
+func (s *ServerMetrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		m := s.metrics.method(info.FullMethod)
+		start := s.metrics.clock.Now()
+		m.started.Inc(1)
+		m.msgReceived.Inc(1)
+		resp, err := handler(ctx, req)
+		if err == nil {
+			m.msgSent.Inc(1)
+		}
+		m.done(start, status.Code(err))
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor recording streaming RPCs
+// and the messages they receive and send.
+func (s *ServerMetrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		m := s.metrics.method(info.FullMethod)
+		start := s.metrics.clock.Now()
+		m.started.Inc(1)
+		err := handler(srv, &serverStream{ServerStream: ss, method: m})
+		m.done(start, status.Code(err))
+		return err
+	}
+}
+
+// serverStream counts the messages of a server stream.
+type serverStream struct {
+	grpc.ServerStream
+	method *methodMetrics
+}
+
+func (s *serverStream) SendMsg(msg any) error {
+	err := s.ServerStream.SendMsg(msg)
+	if err == nil {
+		s.method.msgSent.Inc(1)
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(msg any) error {
+	err := s.ServerStream.RecvMsg(msg)
+	if err == nil {
+		s.method.msgReceived.Inc(1)
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+package guage
+
+import "math"
+
+// Aggregation selects how gauge snapshots taken from several registries are
+// combined.  Unlike counters there is no single right answer: queue depths
+// add up, while a fleet-wide temperature is better described by its max or
+// mean.
+type Aggregation int
+
+const (
+	AggregateSum Aggregation = iota
+	AggregateMin
+	AggregateMax
+	AggregateMean // rounded towards zero for int64 gauges
+)
+
+// Aggregate combines int64 gauge snapshots.  It returns 0 when given none.
+func Aggregate(a Aggregation, snapshots ...int64) int64 {
+	if len(snapshots) == 0 {
+		return 0
+	}
+	res := snapshots[0]
+	for _, v := range snapshots[1:] {
+		switch a {
+		case AggregateMin:
+			res = min(res, v)
+		case AggregateMax:
+			res = max(res, v)
+		default:
+			res += v
+		}
+	}
+	if a == AggregateMean {
+		res /= int64(len(snapshots))
+	}
+	return res
+}
+
+// AggregateFloat64 combines float64 gauge snapshots.  It returns 0 when given
+// none.
+func AggregateFloat64(a Aggregation, snapshots ...float64) float64 {
+	if len(snapshots) == 0 {
+		return 0.0
+	}
+	res := snapshots[0]
+	for _, v := range snapshots[1:] {
+		switch a {
+		case AggregateMin:
+			res = math.Min(res, v)
+		case AggregateMax:
+			res = math.Max(res, v)
+		default:
+			res += v
+		}
+	}
+	if a == AggregateMean {
+		res /= float64(len(snapshots))
+	}
+	return res
+}
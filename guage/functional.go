@@ -0,0 +1,49 @@
+package guage
+
+// FunctionalGauge is a Gauge whose value is computed by a function each time
+// it is read, e.g. to expose a value owned by another component.  Reading it
+// never resets anything.
+type FunctionalGauge struct {
+	value func() int64
+}
+
+// NewFunctionalGauge constructs a new FunctionalGauge.
+func NewFunctionalGauge(f func() int64) Gauge {
+	return &FunctionalGauge{value: f}
+}
+
+// Inc does nothing: the value of a FunctionalGauge is owned by its function.
+func (g *FunctionalGauge) Inc(int64) {}
+
+// Swap returns the current value of the function and changes nothing, as the
+// value is owned by the function.
+func (g *FunctionalGauge) Swap(int64) int64 { return g.value() }
+
+// Snapshot returns the current value of the function.
+func (g *FunctionalGauge) Snapshot() int64 { return g.value() }
+
+// SnapShotAndReset returns the current value of the function, as there is
+// nothing to reset.
+func (g *FunctionalGauge) SnapShotAndReset() int64 { return g.value() }
+
+// FunctionalGaugeFloat64 is a GaugeFloat64 whose value is computed by a
+// function each time it is read.
+type FunctionalGaugeFloat64 struct {
+	value func() float64
+}
+
+// NewFunctionalGaugeFloat64 constructs a new FunctionalGaugeFloat64.
+func NewFunctionalGaugeFloat64(f func() float64) GaugeFloat64 {
+	return &FunctionalGaugeFloat64{value: f}
+}
+
+// Update does nothing: the value of a FunctionalGaugeFloat64 is owned by its
+// function.
+func (g *FunctionalGaugeFloat64) Update(float64) {}
+
+// Snapshot returns the current value of the function.
+func (g *FunctionalGaugeFloat64) Snapshot() float64 { return g.value() }
+
+// SnapshotAndReset returns the current value of the function, as there is
+// nothing to reset.
+func (g *FunctionalGaugeFloat64) SnapshotAndReset() float64 { return g.value() }
@@ -1 +1,34 @@
 package guage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregate(t *testing.T) {
+	assert.Equal(t, int64(9), Aggregate(AggregateSum, 2, 3, 4))
+	assert.Equal(t, int64(2), Aggregate(AggregateMin, 2, 3, 4))
+	assert.Equal(t, int64(4), Aggregate(AggregateMax, 2, 3, 4))
+	assert.Equal(t, int64(3), Aggregate(AggregateMean, 2, 3, 5))
+	assert.Equal(t, int64(0), Aggregate(AggregateMax))
+	assert.Equal(t, 1.5, AggregateFloat64(AggregateMean, 1, 2))
+	assert.Equal(t, -1.0, AggregateFloat64(AggregateMin, 1, -1))
+}
+
+func TestFunctionalGauge(t *testing.T) {
+	var v int64 = 3
+	g := NewFunctionalGauge(func() int64 { return v })
+	assert.Equal(t, int64(3), g.SnapShotAndReset())
+	v = 5
+	assert.Equal(t, int64(5), g.Snapshot())
+	g.Inc(1)
+	assert.Equal(t, int64(5), g.Swap(0))
+	assert.Equal(t, int64(5), g.Snapshot())
+
+	f := NewFunctionalGaugeFloat64(func() float64 { return 0.5 })
+	assert.Equal(t, 0.5, f.SnapshotAndReset())
+	assert.Equal(t, 0.5, f.Snapshot())
+	f.Update(1)
+	assert.Equal(t, 0.5, f.Snapshot())
+}
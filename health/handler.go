@@ -0,0 +1,75 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// CheckResult is the JSON form of a healthcheck's Status.
+type CheckResult struct {
+	Healthy             bool       `json:"healthy"`
+	Error               string     `json:"error,omitempty"`
+	LastChecked         *time.Time `json:"last_checked,omitempty"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	ConsecutiveFailures int64      `json:"consecutive_failures"`
+}
+
+// Response is the JSON body served by a Handler.  Status is "ok" when every
+// healthcheck is healthy and "unhealthy" otherwise.
+type Response struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// NewCheckResult converts a Status to its JSON form.
+func NewCheckResult(s Status) CheckResult {
+	res := CheckResult{
+		Healthy:             s.Healthy(),
+		ConsecutiveFailures: s.ConsecutiveFailures,
+	}
+	if s.Err != nil {
+		res.Error = s.Err.Error()
+	}
+	if !s.LastChecked.IsZero() {
+		t := s.LastChecked
+		res.LastChecked = &t
+	}
+	if !s.LastSuccess.IsZero() {
+		t := s.LastSuccess
+		res.LastSuccess = &t
+	}
+	return res
+}
+
+// Handler serves the latest status of the healthchecks of a registry as a
+// Response, with status 200 when all are healthy and 503 otherwise.  It does
+// not run checks itself; run them with Run.  Mount it at e.g. "/healthz".
+type Handler struct {
+	registry Registry
+}
+
+// NewHandler constructs a Handler for the healthchecks of r.
+func NewHandler(r Registry) *Handler {
+	return &Handler{registry: r}
+}
+
+// ServeHTTP writes the Response.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	res := Response{Status: "ok", Checks: make(map[string]CheckResult)}
+	code := http.StatusOK
+	for name, check := range Healthchecks(h.registry) {
+		result := NewCheckResult(check.Status())
+		if !result.Healthy {
+			res.Status = "unhealthy"
+			code = http.StatusServiceUnavailable
+		}
+		res.Checks[name] = result
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	if r.Method != http.MethodHead {
+		json.NewEncoder(w).Encode(res)
+	}
+}
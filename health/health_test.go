@@ -0,0 +1,161 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/someview/go-metrics/clock/clocktest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registry is a minimal Registry, as this package cannot import reporter.
+type registry map[string]interface{}
+
+func (r registry) Each(f func(string, interface{})) {
+	for name, i := range r {
+		f(name, i)
+	}
+}
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestHealthcheck_Check(t *testing.T) {
+	c := clocktest.NewClock(epoch)
+	var fail atomic.Bool
+	h := NewHealthcheck(func(context.Context) error {
+		if fail.Load() {
+			return errors.New("down")
+		}
+		return nil
+	}, WithClock(c))
+
+	status := h.Status()
+	assert.False(t, status.Healthy())
+	assert.ErrorIs(t, status.Err, ErrNotChecked)
+
+	require.NoError(t, h.Check(context.Background()))
+	status = h.Status()
+	assert.True(t, status.Healthy())
+	assert.Equal(t, epoch, status.LastChecked)
+	assert.Equal(t, epoch, status.LastSuccess)
+
+	fail.Store(true)
+	c.Add(time.Minute)
+	assert.EqualError(t, h.Check(context.Background()), "down")
+	c.Add(time.Minute)
+	h.Check(context.Background())
+	status = h.Status()
+	assert.False(t, status.Healthy())
+	assert.Equal(t, int64(2), status.ConsecutiveFailures)
+	assert.Equal(t, epoch.Add(2*time.Minute), status.LastChecked)
+	assert.Equal(t, epoch, status.LastSuccess)
+
+	fail.Store(false)
+	require.NoError(t, h.Check(context.Background()))
+	assert.Equal(t, int64(0), h.Status().ConsecutiveFailures)
+}
+
+func TestHealthcheck_Timeout(t *testing.T) {
+	c := clocktest.NewClock(epoch)
+	cancelled := make(chan struct{})
+	h := NewHealthcheck(func(ctx context.Context) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	}, WithClock(c), WithTimeout(time.Second))
+
+	done := make(chan error)
+	go func() { done <- h.Check(context.Background()) }()
+	c.BlockUntil(1)
+	c.Add(time.Second)
+	assert.ErrorIs(t, <-done, ErrTimeout)
+	<-cancelled
+	assert.Equal(t, int64(1), h.Status().ConsecutiveFailures)
+}
+
+func TestHealthcheck_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := NewHealthcheck(func(ctx context.Context) error {
+		cancel()
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	require.NoError(t, NewHealthcheck(func(context.Context) error { return nil }).Check(ctx))
+	assert.ErrorIs(t, h.Check(ctx), context.Canceled)
+	assert.ErrorIs(t, h.Status().Err, ErrNotChecked, "cancelled checks are not recorded")
+	assert.Equal(t, int64(0), h.Status().ConsecutiveFailures)
+}
+
+func TestHealthcheck_Panic(t *testing.T) {
+	h := NewHealthcheck(func(context.Context) error { panic("boom") })
+	assert.ErrorContains(t, h.Check(context.Background()), "boom")
+}
+
+func TestRunWithClock(t *testing.T) {
+	c := clocktest.NewClock(epoch)
+	var checks atomic.Int64
+	r := registry{
+		"db":    NewHealthcheck(func(context.Context) error { checks.Add(1); return nil }, WithClock(c)),
+		"other": 42,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		RunWithClock(ctx, r, 10*time.Second, c)
+		close(done)
+	}()
+	c.BlockUntil(1)
+	assert.Eventually(t, func() bool { return checks.Load() == 1 }, time.Second, time.Millisecond)
+	c.Add(10 * time.Second)
+	assert.Eventually(t, func() bool { return checks.Load() == 2 }, time.Second, time.Millisecond)
+	cancel()
+	c.Add(10 * time.Second)
+	<-done
+}
+
+func TestHandler(t *testing.T) {
+	c := clocktest.NewClock(epoch)
+	db := NewHealthcheck(func(context.Context) error { return nil }, WithClock(c))
+	cache := NewHealthcheck(func(context.Context) error { return errors.New("refused") }, WithClock(c))
+	r := registry{"db": db}
+	db.Check(context.Background())
+
+	serve := func() (*httptest.ResponseRecorder, Response) {
+		rec := httptest.NewRecorder()
+		NewHandler(r).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		var res Response
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return rec, res
+	}
+
+	rec, res := serve()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "ok", res.Status)
+	require.Contains(t, res.Checks, "db")
+	assert.True(t, res.Checks["db"].Healthy)
+	assert.Equal(t, epoch, *res.Checks["db"].LastSuccess)
+
+	r["cache"] = cache
+	rec, res = serve()
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "unhealthy", res.Status)
+	assert.Equal(t, ErrNotChecked.Error(), res.Checks["cache"].Error)
+	assert.Nil(t, res.Checks["cache"].LastChecked)
+
+	cache.Check(context.Background())
+	cache.Check(context.Background())
+	_, res = serve()
+	assert.Equal(t, CheckResult{
+		Error:               "refused",
+		LastChecked:         &epoch,
+		ConsecutiveFailures: 2,
+	}, res.Checks["cache"])
+}
@@ -0,0 +1,150 @@
+// Package health runs healthchecks kept in a reporter.Registry alongside
+// metrics and serves their results over HTTP.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/someview/go-metrics/clock"
+)
+
+// ErrNotChecked is the error of a healthcheck that has not completed a check
+// yet.
+var ErrNotChecked = errors.New("health: not checked yet")
+
+// ErrTimeout is recorded when a check does not return within its timeout.
+var ErrTimeout = errors.New("health: check timed out")
+
+// Healthcheck holds the outcome of the latest run of a check function.
+// Reporters read it as two gauges: whether it is healthy and how many checks
+// in a row have failed.
+type Healthcheck interface {
+	// Check runs the check function and records its outcome, which it
+	// returns.
+	Check(ctx context.Context) error
+	// Status returns the outcome of the latest check.
+	Status() Status
+}
+
+// Status is the outcome of the latest check of a Healthcheck.
+type Status struct {
+	// Err is nil after a successful check and ErrNotChecked before the
+	// first check completes.
+	Err error
+	// LastChecked is when the latest check completed and LastSuccess when
+	// the latest successful one did.  Both are zero until then.
+	LastChecked time.Time
+	LastSuccess time.Time
+	// ConsecutiveFailures is the number of checks that failed since the
+	// latest successful one.
+	ConsecutiveFailures int64
+}
+
+// Healthy reports whether the latest check succeeded.
+func (s Status) Healthy() bool { return s.Err == nil }
+
+// Option configures a Healthcheck.
+type Option func(*StandardHealthcheck)
+
+// WithTimeout bounds each check, DefaultTimeout by default.  A check still
+// running at the timeout has its context cancelled and is recorded as
+// failed with ErrTimeout without waiting for it to return.  A non-positive
+// timeout disables the bound.
+func WithTimeout(d time.Duration) Option {
+	return func(h *StandardHealthcheck) {
+		h.timeout = d
+	}
+}
+
+// WithClock sets the clock used to time out checks and to timestamp them.
+func WithClock(c clock.Clock) Option {
+	return func(h *StandardHealthcheck) {
+		h.clock = c
+	}
+}
+
+// DefaultTimeout is the timeout of a check unless set with WithTimeout.
+const DefaultTimeout = 5 * time.Second
+
+// StandardHealthcheck is the standard implementation of a Healthcheck.
+type StandardHealthcheck struct {
+	check   func(context.Context) error
+	timeout time.Duration
+	clock   clock.Clock
+	mutex   sync.RWMutex
+	status  Status
+}
+
+// NewHealthcheck constructs a Healthcheck running check.  It is unhealthy,
+// with ErrNotChecked, until its first check completes.
+func NewHealthcheck(check func(context.Context) error, opts ...Option) Healthcheck {
+	h := &StandardHealthcheck{
+		check:   check,
+		timeout: DefaultTimeout,
+		clock:   clock.System(),
+		status:  Status{Err: ErrNotChecked},
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Check runs the check function and records its outcome.  A panic in the
+// check function is recorded as a failure.  A check cut short because ctx
+// is done, e.g. on shutdown, is not recorded.
+func (h *StandardHealthcheck) Check(ctx context.Context) error {
+	err := h.run(ctx)
+	if ctx.Err() != nil {
+		return err
+	}
+	now := h.clock.Now()
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.status.Err = err
+	h.status.LastChecked = now
+	if err == nil {
+		h.status.LastSuccess = now
+		h.status.ConsecutiveFailures = 0
+	} else {
+		h.status.ConsecutiveFailures++
+	}
+	return err
+}
+
+// Status returns the outcome of the latest check.
+func (h *StandardHealthcheck) Status() Status {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.status
+}
+
+func (h *StandardHealthcheck) run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("health: check panicked: %v", p)
+			}
+		}()
+		done <- h.check(ctx)
+	}()
+	var timeout <-chan time.Time
+	if h.timeout > 0 {
+		timeout = h.clock.After(h.timeout)
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-timeout:
+		return ErrTimeout
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
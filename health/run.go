@@ -0,0 +1,60 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/someview/go-metrics/clock"
+)
+
+// Registry is the part of a reporter.Registry healthchecks are read from.
+type Registry interface {
+	Each(func(string, interface{}))
+}
+
+// Healthchecks returns the healthchecks of the registry by name.
+func Healthchecks(r Registry) map[string]Healthcheck {
+	checks := make(map[string]Healthcheck)
+	r.Each(func(name string, i interface{}) {
+		if h, ok := i.(Healthcheck); ok {
+			checks[name] = h
+		}
+	})
+	return checks
+}
+
+// CheckAll runs every healthcheck of the registry concurrently and waits for
+// them to complete.
+func CheckAll(ctx context.Context, r Registry) {
+	var wg sync.WaitGroup
+	for _, h := range Healthchecks(r) {
+		wg.Add(1)
+		go func(h Healthcheck) {
+			defer wg.Done()
+			h.Check(ctx)
+		}(h)
+	}
+	wg.Wait()
+}
+
+// Run runs every healthcheck of the registry at once and then at each
+// interval until ctx is done.  Healthchecks registered in the meantime are
+// picked up at the next interval.
+func Run(ctx context.Context, r Registry, interval time.Duration) {
+	RunWithClock(ctx, r, interval, clock.System())
+}
+
+// RunWithClock is like Run but waits for ticks of the given clock.
+func RunWithClock(ctx context.Context, r Registry, interval time.Duration, c clock.Clock) {
+	ticker := c.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		CheckAll(ctx, r)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
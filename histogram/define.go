@@ -8,3 +8,11 @@ type Histogram interface {
 	Sample() sample.Sample
 	Update(int64)
 }
+
+// Float64Histograms calculate distribution statistics from a series of
+// float64 values.
+type Float64Histogram interface {
+	Clear()
+	Sample() sample.Float64Sample
+	Update(float64)
+}
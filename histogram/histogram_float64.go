@@ -0,0 +1,27 @@
+package histogram
+
+import (
+	"github.com/someview/go-metrics/sample"
+)
+
+// StandardFloat64Histogram is the standard implementation of a
+// Float64Histogram and uses a Float64Sample to bound its memory use.
+type StandardFloat64Histogram struct {
+	sample sample.Float64Sample
+}
+
+func NewFloat64Histogram(s sample.Float64Sample) Float64Histogram {
+	return &StandardFloat64Histogram{
+		sample: s,
+	}
+}
+
+func (h *StandardFloat64Histogram) Update(v float64) {
+	h.sample.Update(v)
+}
+
+// Clear clears the histogram and its sample.
+func (h *StandardFloat64Histogram) Clear() { h.sample.Clear() }
+
+// Sample returns the Float64Sample underlying the histogram.
+func (h *StandardFloat64Histogram) Sample() sample.Float64Sample { return h.sample }
@@ -12,3 +12,11 @@ func BenchmarkHistogram(b *testing.B) {
 		h.Update(int64(i))
 	}
 }
+
+func BenchmarkFloat64Histogram(b *testing.B) {
+	h := NewFloat64Histogram(sample.NewSlidingWindowFloat64Sample(100))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Update(float64(i) / 3)
+	}
+}
@@ -0,0 +1,143 @@
+package histogram
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/someview/go-metrics/clock"
+	"github.com/someview/go-metrics/internal/ring"
+	"github.com/someview/go-metrics/sample"
+)
+
+// WindowBuckets is the number of sub-windows each window of a
+// MultiWindowHistogram rotates through.  A window of length d therefore
+// covers between the last 5d/6 and d of updates.
+const WindowBuckets = 6
+
+// WindowedHistograms are Histograms that also keep the values of several
+// trailing time windows, e.g. the last 1, 5 and 15 minutes.  Window
+// snapshots are read without resetting anything.
+type WindowedHistogram interface {
+	Histogram
+	Windows() []time.Duration
+	WindowSnapshot(time.Duration) sample.SampleSnapshot
+}
+
+// WindowName formats a window length as a metric name suffix such as "30s",
+// "1m" or "1h".
+func WindowName(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case d%time.Second == 0:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+	return d.String()
+}
+
+// MultiWindowHistogram is the standard implementation of a
+// WindowedHistogram.  Besides the sample it reports and resets like a
+// StandardHistogram, each window rotates through WindowBuckets samples made
+// by newSample, and a window snapshot merges the samples that are still
+// inside the window.
+type MultiWindowHistogram struct {
+	sample  sample.Sample
+	clock   clock.Clock
+	windows []*timeWindow
+}
+
+type timeWindow struct {
+	mutex   sync.Mutex
+	length  time.Duration
+	samples *ring.Ring[sample.Sample]
+}
+
+// NewMultiWindowHistogram constructs a histogram that reports s and keeps
+// samples made by newSample for each of the given windows.
+func NewMultiWindowHistogram(s sample.Sample, newSample func() sample.Sample, windows ...time.Duration) WindowedHistogram {
+	return NewMultiWindowHistogramWithClock(clock.System(), s, newSample, windows...)
+}
+
+// NewMultiWindowHistogramWithClock is like NewMultiWindowHistogram but rotates
+// windows according to the given clock.
+func NewMultiWindowHistogramWithClock(c clock.Clock, s sample.Sample, newSample func() sample.Sample, windows ...time.Duration) WindowedHistogram {
+	h := &MultiWindowHistogram{sample: s, clock: c}
+	for _, d := range windows {
+		if d < WindowBuckets {
+			panic(fmt.Sprintf("histogram: window %v is too short", d))
+		}
+		h.windows = append(h.windows, &timeWindow{
+			length:  d,
+			samples: ring.New(d, WindowBuckets, newSample, clearSample),
+		})
+	}
+	return h
+}
+
+// Update updates the reported sample and every window.
+func (h *MultiWindowHistogram) Update(v int64) {
+	h.sample.Update(v)
+	now := h.clock.Now()
+	for _, w := range h.windows {
+		w.update(now, v)
+	}
+}
+
+// Clear clears the histogram, its sample and every window.
+func (h *MultiWindowHistogram) Clear() {
+	h.sample.Clear()
+	for _, w := range h.windows {
+		w.clear()
+	}
+}
+
+// Sample returns the Sample underlying the histogram, which reporters read
+// and reset.  The windows are unaffected by it.
+func (h *MultiWindowHistogram) Sample() sample.Sample { return h.sample }
+
+// Windows returns the window lengths in the order they were given.
+func (h *MultiWindowHistogram) Windows() []time.Duration {
+	res := make([]time.Duration, len(h.windows))
+	for i, w := range h.windows {
+		res[i] = w.length
+	}
+	return res
+}
+
+// WindowSnapshot returns a snapshot of the values of the window of length d,
+// or an empty snapshot if the histogram has no such window.
+func (h *MultiWindowHistogram) WindowSnapshot(d time.Duration) sample.SampleSnapshot {
+	for _, w := range h.windows {
+		if w.length == d {
+			return w.snapshot(h.clock.Now())
+		}
+	}
+	return sample.MergeSnapshots()
+}
+
+func clearSample(s *sample.Sample) { (*s).Clear() }
+
+func (w *timeWindow) update(now time.Time, v int64) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	(*w.samples.At(now)).Update(v)
+}
+
+func (w *timeWindow) snapshot(now time.Time) sample.SampleSnapshot {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	snapshots := make([]sample.SampleSnapshot, 0, WindowBuckets)
+	w.samples.Each(now, func(s *sample.Sample) {
+		snapshots = append(snapshots, (*s).Snapshot())
+	})
+	return sample.MergeSnapshots(snapshots...)
+}
+
+func (w *timeWindow) clear() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.samples.Clear()
+}
@@ -0,0 +1,73 @@
+package histogram
+
+import (
+	"testing"
+	"time"
+
+	"github.com/someview/go-metrics/clock/clocktest"
+	"github.com/someview/go-metrics/sample"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiWindowHistogram(t *testing.T) {
+	c := clocktest.NewClock(time.Unix(0, 0))
+	newSample := func() sample.Sample { return sample.NewSlidingWindowSample(100) }
+	h := NewMultiWindowHistogramWithClock(c, sample.NewSlidingWindowSample(100), newSample,
+		time.Minute, 5*time.Minute, 15*time.Minute)
+	assert.Equal(t, []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}, h.Windows())
+
+	// One update a minute for ten minutes.
+	for i := int64(1); i <= 10; i++ {
+		h.Update(i)
+		c.Add(time.Minute)
+	}
+	assert.Equal(t, int64(10), h.Sample().SnapshotAndReset().ReqCount())
+	// The 1m window covers between 50s and 60s, which holds no update.
+	assert.Equal(t, int64(0), h.WindowSnapshot(time.Minute).ReqCount())
+	h.Update(100)
+	one := h.WindowSnapshot(time.Minute)
+	assert.Equal(t, int64(1), one.ReqCount())
+	assert.Equal(t, int64(100), one.ExactMax())
+
+	five := h.WindowSnapshot(5 * time.Minute)
+	assert.Equal(t, int64(5), five.ReqCount())
+	assert.Equal(t, int64(7), five.ExactMin())
+	fifteen := h.WindowSnapshot(15 * time.Minute)
+	assert.Equal(t, int64(11), fifteen.ReqCount())
+	assert.Equal(t, 100.0, fifteen.Percentile(1))
+
+	// Reading windows resets nothing, while the reported sample is
+	// independent of them.
+	assert.Equal(t, int64(11), h.WindowSnapshot(15*time.Minute).ReqCount())
+	assert.Equal(t, int64(1), h.Sample().Snapshot().ReqCount())
+	assert.Equal(t, int64(0), h.WindowSnapshot(time.Hour).ReqCount())
+
+	c.Add(15 * time.Minute)
+	assert.Equal(t, int64(0), h.WindowSnapshot(15*time.Minute).ReqCount())
+	h.Update(1)
+	h.Clear()
+	assert.Equal(t, int64(0), h.WindowSnapshot(time.Minute).ReqCount())
+}
+
+func TestMultiWindowHistogramBeforeUnixEpoch(t *testing.T) {
+	for _, start := range []time.Time{time.Unix(-90, 0), {}} {
+		c := clocktest.NewClock(start)
+		newSample := func() sample.Sample { return sample.NewSlidingWindowSample(100) }
+		h := NewMultiWindowHistogramWithClock(c, sample.NewSlidingWindowSample(100), newSample, time.Minute)
+		for i := int64(1); i <= 12; i++ {
+			h.Update(i)
+			c.Add(10 * time.Second)
+		}
+		w := h.WindowSnapshot(time.Minute)
+		assert.Equal(t, int64(5), w.ReqCount(), start)
+		assert.Equal(t, int64(8), w.ExactMin(), start)
+	}
+}
+
+func TestWindowName(t *testing.T) {
+	assert.Equal(t, "1m", WindowName(time.Minute))
+	assert.Equal(t, "15m", WindowName(15*time.Minute))
+	assert.Equal(t, "2h", WindowName(2*time.Hour))
+	assert.Equal(t, "90s", WindowName(90*time.Second))
+	assert.Equal(t, "1.5s", WindowName(1500*time.Millisecond))
+}
@@ -0,0 +1,111 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Series is the JSON form of a queried series.
+type Series struct {
+	Key
+	Points []Point `json:"points"`
+	Stats  *Stats  `json:"stats,omitempty"`
+}
+
+// Handler serves the history of a Store as JSON.  Without parameters it
+// lists the keys of every series.  With a metric it returns
+// {"series": [...]}, one Series per field or only the requested one.  Query
+// parameters:
+//
+//	metric   the metric, e.g. "latency"
+//	field    the field, e.g. "99%"; every field of the metric by default
+//	from     the start, RFC 3339 or a duration before now such as "15m";
+//	         an hour ago by default
+//	to       the end, in the same forms; now by default
+//	fn       "range", the default, or "rate"
+type Handler struct {
+	store *Store
+}
+
+// NewHandler constructs a Handler serving s.  Mount it at e.g.
+// "/debug/metrics/history".
+func NewHandler(s *Store) *Handler {
+	return &Handler{store: s}
+}
+
+// ServeHTTP serves the query.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	metric := q.Get("metric")
+	if metric == "" {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"keys": h.store.Keys()})
+		return
+	}
+	now := h.store.clock.Now()
+	from, err := parseTime(q.Get("from"), now, now.Add(-time.Hour))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	to, err := parseTime(q.Get("to"), now, now)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	query := h.store.Range
+	switch fn := q.Get("fn"); fn {
+	case "", "range":
+	case "rate":
+		query = h.store.Rate
+	default:
+		writeError(w, fmt.Errorf("unknown fn %q", fn))
+		return
+	}
+
+	series := []Series{}
+	for _, k := range h.store.Keys() {
+		if k.Metric != metric || (q.Has("field") && k.Field != q.Get("field")) {
+			continue
+		}
+		points := query(k, from, to)
+		if points == nil {
+			points = []Point{}
+		}
+		s := Series{Key: k, Points: points}
+		if stats, ok := Summarize(points); ok {
+			s.Stats = &stats
+		}
+		series = append(series, s)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"series": series})
+}
+
+// parseTime parses an RFC 3339 time or a duration before now, returning def
+// for an empty string.
+func parseTime(s string, now, def time.Time) (time.Time, error) {
+	if s == "" {
+		return def, nil
+	}
+	if d, err := time.ParseDuration(strings.TrimPrefix(s, "-")); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+	return t, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,171 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/someview/go-metrics/clock/clocktest"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/someview/go-metrics/sample"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var epoch = time.Unix(0, 0).UTC()
+
+func snapshot(requests, inflight int64) *reporter.RegistrySnapshot {
+	s := reporter.NewRegistrySnapshot()
+	s.Counters["requests"] = requests
+	s.Gauges["inflight"] = inflight
+	return s
+}
+
+var (
+	requests = Key{Metric: "requests", Field: "count"}
+	inflight = Key{Metric: "inflight", Field: "value"}
+)
+
+func TestStore_Range(t *testing.T) {
+	s := NewStore(WithTiers(Tier{Points: 3}, Tier{Resolution: time.Minute, Points: 10}))
+	for i := int64(0); i < 6; i++ {
+		s.Record(epoch.Add(time.Duration(i)*30*time.Second), snapshot(i*10, i))
+	}
+	assert.Equal(t, []Key{inflight, requests}, s.Keys())
+
+	// The raw tier holds the last three snapshots.
+	points := s.Range(inflight, epoch.Add(90*time.Second), epoch.Add(time.Hour))
+	assert.Equal(t, []Point{
+		{Time: epoch.Add(90 * time.Second), Value: 3, Min: 3, Max: 3, Count: 1},
+		{Time: epoch.Add(120 * time.Second), Value: 4, Min: 4, Max: 4, Count: 1},
+		{Time: epoch.Add(150 * time.Second), Value: 5, Min: 5, Max: 5, Count: 1},
+	}, points)
+
+	// Older ranges are served from the rollup.
+	points = s.Range(inflight, epoch, epoch.Add(time.Hour))
+	assert.Equal(t, []Point{
+		{Time: epoch, Value: 0.5, Min: 0, Max: 1, Count: 2},
+		{Time: epoch.Add(time.Minute), Value: 2.5, Min: 2, Max: 3, Count: 2},
+		{Time: epoch.Add(2 * time.Minute), Value: 4.5, Min: 4, Max: 5, Count: 2},
+	}, points)
+
+	stats, ok := s.Stats(inflight, epoch, epoch.Add(time.Hour))
+	require.True(t, ok)
+	assert.Equal(t, Stats{Avg: 2.5, Min: 0, Max: 5, Count: 6}, stats)
+	_, ok = s.Stats(Key{Metric: "missing"}, epoch, epoch.Add(time.Hour))
+	assert.False(t, ok)
+}
+
+func TestStore_Rate(t *testing.T) {
+	s := NewStore()
+	for i, v := range []int64{0, 100, 300, 50} {
+		s.Record(epoch.Add(time.Duration(i)*10*time.Second), snapshot(v, 0))
+	}
+	var rates []float64
+	for _, p := range s.Rate(requests, epoch, epoch.Add(time.Minute)) {
+		rates = append(rates, p.Value)
+	}
+	assert.Equal(t, []float64{10, 20, 5}, rates, "decreases are resets")
+	assert.Nil(t, s.Rate(requests, epoch, epoch))
+}
+
+func TestStore_MaxSeries(t *testing.T) {
+	s := NewStore(WithMaxSeries(1))
+	s.Record(epoch, snapshot(1, 1))
+	assert.Len(t, s.Keys(), 1)
+	assert.Equal(t, int64(1), s.Dropped())
+}
+
+func TestNewStore_InvalidTiers(t *testing.T) {
+	assert.Panics(t, func() { NewStore(WithTiers()) })
+	assert.Panics(t, func() { NewStore(WithTiers(Tier{Points: 10}, Tier{Resolution: time.Minute})) })
+}
+
+func TestStore_Histogram(t *testing.T) {
+	s := NewStore()
+	snap := reporter.NewRegistrySnapshot()
+	h := sample.NewSlidingWindowSample(2)
+	for i := 0; i < 3; i++ {
+		h.Update(5)
+	}
+	snap.Histograms["latency"] = h.Snapshot()
+	s.Record(epoch, snap)
+	assert.Equal(t, []Point{{Time: epoch, Value: 5, Min: 5, Max: 5, Count: 1}},
+		s.Range(Key{Metric: "latency", Field: "99%"}, epoch, epoch))
+	assert.Equal(t, []Point{{Time: epoch, Value: 3, Min: 3, Max: 3, Count: 1}},
+		s.Range(Key{Metric: "latency", Field: "reqCount"}, epoch, epoch))
+	assert.Equal(t, []Point{{Time: epoch, Value: 2, Min: 2, Max: 2, Count: 1}},
+		s.Range(Key{Metric: "latency", Field: "count"}, epoch, epoch))
+	for _, r := range s.series[Key{Metric: "latency", Field: "count"}] {
+		assert.LessOrEqual(t, cap(r.points), 8, "rings grow as points are added")
+	}
+}
+
+func TestStore_Run(t *testing.T) {
+	c := clocktest.NewClock(epoch)
+	s := NewStore(WithClock(c))
+	r := reporter.NewRegistry()
+	reporter.GetOrRegisterCounter("requests", r).Inc(3)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx, r, time.Second)
+	c.BlockUntil(1)
+	c.Add(time.Second)
+	assert.Eventually(t, func() bool {
+		return len(s.Range(requests, epoch, epoch.Add(time.Minute))) == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestHandler(t *testing.T) {
+	c := clocktest.NewClock(epoch.Add(time.Minute))
+	s := NewStore(WithClock(c))
+	s.Record(epoch, snapshot(0, 2))
+	s.Record(epoch.Add(30*time.Second), snapshot(60, 4))
+	h := NewHandler(s)
+
+	get := func(query string) (int, map[string]json.RawMessage) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/metrics/history?"+query, nil))
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		var body map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return rec.Code, body
+	}
+
+	code, body := get("")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `[{"metric":"inflight","field":"value"},{"metric":"requests","field":"count"}]`, string(body["keys"]))
+
+	code, body = get("metric=requests&fn=rate&from=2m")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `[{
+		"metric": "requests", "field": "count",
+		"points": [{"t": "1970-01-01T00:00:30Z", "v": 2, "min": 2, "max": 2, "n": 1}],
+		"stats": {"avg": 2, "min": 2, "max": 2, "n": 1}
+	}]`, string(body["series"]))
+
+	code, body = get("metric=inflight&field=value&from=1970-01-01T00:00:10Z")
+	assert.Equal(t, http.StatusOK, code)
+	var series []Series
+	require.NoError(t, json.Unmarshal(body["series"], &series))
+	require.Len(t, series, 1)
+	assert.Len(t, series[0].Points, 1)
+
+	code, body = get("metric=inflight&to=30s")
+	assert.Equal(t, http.StatusOK, code)
+	require.NoError(t, json.Unmarshal(body["series"], &series))
+	assert.Len(t, series[0].Points, 2)
+
+	code, body = get("metric=inflight&field=missing")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `[]`, string(body["series"]))
+
+	code, _ = get("metric=inflight&from=yesterday")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, body = get("metric=inflight&fn=sum")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.JSONEq(t, `"unknown fn \"sum\""`, string(body["error"]))
+}
@@ -0,0 +1,342 @@
+// Package history keeps a bounded in-memory history of registry snapshots,
+// rolled up into coarser points as it ages, and serves it as JSON.
+package history
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/someview/go-metrics/clock"
+	"github.com/someview/go-metrics/reporter"
+)
+
+// Key identifies a series: a field of a metric as named by
+// reporter.RegistrySnapshot.Values, e.g. {"latency", "99%"} or
+// {"requests", "count"}.  The number of updates of a histogram is its
+// "reqCount" field; its "count" is the number of values it retained.
+type Key struct {
+	Metric string `json:"metric"`
+	Field  string `json:"field"`
+}
+
+// Point is a value of a series.  A raw point holds the value of one
+// snapshot; a rolled-up point holds the mean, minimum and maximum of the
+// Count values recorded during its resolution, starting at Time.
+type Point struct {
+	Time  time.Time `json:"t"`
+	Value float64   `json:"v"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Count int64     `json:"n"`
+}
+
+func (p *Point) merge(o Point) {
+	n := p.Count + o.Count
+	p.Value = (p.Value*float64(p.Count) + o.Value*float64(o.Count)) / float64(n)
+	p.Min = math.Min(p.Min, o.Min)
+	p.Max = math.Max(p.Max, o.Max)
+	p.Count = n
+}
+
+// Tier is a ring of points of a series.  A zero resolution keeps one raw
+// point per recorded snapshot; otherwise points are rolled up per interval
+// of the resolution.
+type Tier struct {
+	Resolution time.Duration
+	Points     int
+}
+
+// DefaultTiers keep the last 120 snapshots, a day of 5 minute rollups and a
+// week of hourly rollups.
+var DefaultTiers = []Tier{
+	{Resolution: 0, Points: 120},
+	{Resolution: 5 * time.Minute, Points: 288},
+	{Resolution: time.Hour, Points: 168},
+}
+
+// DefaultMaxSeries bounds the number of series unless set with
+// WithMaxSeries.  A series takes 56 bytes per point as its tiers fill up,
+// about 32 KiB with DefaultTiers.
+const DefaultMaxSeries = 4096
+
+// Store keeps the history of the series of recorded snapshots.  It is safe
+// for concurrent use.
+type Store struct {
+	tiers     []Tier
+	maxSeries int
+	clock     clock.Clock
+
+	mutex   sync.RWMutex
+	series  map[Key][]*ring
+	dropped int64
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithTiers sets the tiers each series is kept in, DefaultTiers by default.
+// Tiers should be ordered from the finest resolution to the coarsest.
+func WithTiers(tiers ...Tier) Option {
+	return func(s *Store) {
+		s.tiers = tiers
+	}
+}
+
+// WithMaxSeries bounds the number of series, DefaultMaxSeries by default.
+// Values of further series are dropped.
+func WithMaxSeries(n int) Option {
+	return func(s *Store) {
+		s.maxSeries = n
+	}
+}
+
+// WithClock sets the clock used to timestamp snapshots taken by Run and to
+// resolve relative times in queries.
+func WithClock(c clock.Clock) Option {
+	return func(s *Store) {
+		s.clock = c
+	}
+}
+
+// NewStore constructs an empty Store.  It panics unless there is at least
+// one tier and every tier keeps at least one point.
+func NewStore(opts ...Option) *Store {
+	s := &Store{
+		tiers:     DefaultTiers,
+		maxSeries: DefaultMaxSeries,
+		clock:     clock.System(),
+		series:    make(map[Key][]*ring),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if len(s.tiers) == 0 {
+		panic("history: no tiers")
+	}
+	for _, t := range s.tiers {
+		if t.Points <= 0 {
+			panic(fmt.Sprintf("history: tier of resolution %v keeps %d points", t.Resolution, t.Points))
+		}
+	}
+	return s
+}
+
+// Record adds the values of snapshot, taken at t, to the history.
+func (s *Store) Record(t time.Time, snapshot *reporter.RegistrySnapshot) {
+	values := snapshot.Values()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for metric, fields := range values {
+		for field, v := range fields {
+			f, ok := toFloat(v)
+			if !ok {
+				continue
+			}
+			s.add(Key{Metric: metric, Field: field}, t, f)
+		}
+	}
+}
+
+// Run records a snapshot of r, taken with reporter.SnapshotRegistry, at each
+// interval until ctx is done.  See SnapshotRegistry for sharing r with other
+// readers.
+func (s *Store) Run(ctx context.Context, r reporter.Registry, interval time.Duration) {
+	ticker := s.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-ticker.C():
+			s.Record(t, reporter.SnapshotRegistry(r))
+		}
+	}
+}
+
+// Dropped returns the number of values dropped because the store already
+// held WithMaxSeries series.
+func (s *Store) Dropped() int64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.dropped
+}
+
+// Keys returns the keys of every series, sorted.
+func (s *Store) Keys() []Key {
+	s.mutex.RLock()
+	keys := make([]Key, 0, len(s.series))
+	for k := range s.series {
+		keys = append(keys, k)
+	}
+	s.mutex.RUnlock()
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Metric != keys[j].Metric {
+			return keys[i].Metric < keys[j].Metric
+		}
+		return keys[i].Field < keys[j].Field
+	})
+	return keys
+}
+
+// Range returns the points of the series between from and to, inclusive,
+// from the finest tier that still holds from.
+func (s *Store) Range(k Key, from, to time.Time) []Point {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	rings, ok := s.series[k]
+	if !ok {
+		return nil
+	}
+	r := rings[len(rings)-1]
+	for _, candidate := range rings {
+		if candidate.holds(from) {
+			r = candidate
+			break
+		}
+	}
+	return r.between(from, to)
+}
+
+// Rate returns the per-second increase between consecutive points of the
+// range, e.g. the request rate of a counter's "count" series.  A decrease
+// is taken as a reset to zero.  Rates of rolled-up points are those of their
+// means.  It does not apply to series of histograms and gauges, which
+// reporter.SnapshotRegistry resets: a histogram's "reqCount" series already
+// holds the updates since the previous snapshot.
+func (s *Store) Rate(k Key, from, to time.Time) []Point {
+	points := s.Range(k, from, to)
+	if len(points) < 2 {
+		return nil
+	}
+	rates := make([]Point, 0, len(points)-1)
+	for i := 1; i < len(points); i++ {
+		prev, p := points[i-1], points[i]
+		dt := p.Time.Sub(prev.Time).Seconds()
+		if dt <= 0 {
+			continue
+		}
+		dv := p.Value - prev.Value
+		if dv < 0 {
+			dv = p.Value
+		}
+		rate := dv / dt
+		rates = append(rates, Point{Time: p.Time, Value: rate, Min: rate, Max: rate, Count: 1})
+	}
+	return rates
+}
+
+// Stats summarizes points over time.
+type Stats struct {
+	Avg   float64 `json:"avg"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int64   `json:"n"`
+}
+
+// Summarize returns the mean, weighted by Count, the minimum and the maximum
+// of points, and the number of values they cover.  It returns false if there
+// are no points.
+func Summarize(points []Point) (Stats, bool) {
+	if len(points) == 0 {
+		return Stats{}, false
+	}
+	p := points[0]
+	for _, o := range points[1:] {
+		p.merge(o)
+	}
+	return Stats{Avg: p.Value, Min: p.Min, Max: p.Max, Count: p.Count}, true
+}
+
+// Stats summarizes the range of the series as described for Summarize.
+func (s *Store) Stats(k Key, from, to time.Time) (Stats, bool) {
+	return Summarize(s.Range(k, from, to))
+}
+
+func (s *Store) add(k Key, t time.Time, v float64) {
+	rings, ok := s.series[k]
+	if !ok {
+		if len(s.series) >= s.maxSeries {
+			s.dropped++
+			return
+		}
+		rings = make([]*ring, len(s.tiers))
+		for i, tier := range s.tiers {
+			rings[i] = newRing(tier)
+		}
+		s.series[k] = rings
+	}
+	p := Point{Time: t, Value: v, Min: v, Max: v, Count: 1}
+	for _, r := range rings {
+		r.add(p)
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, !math.IsNaN(v) && !math.IsInf(v, 0)
+	}
+	return 0, false
+}
+
+// ring holds the latest points of a tier, oldest first from start.  Its
+// points grow as they are added, up to size.
+type ring struct {
+	resolution time.Duration
+	size       int
+	points     []Point
+	start, n   int
+}
+
+func newRing(t Tier) *ring {
+	return &ring{resolution: t.Resolution, size: t.Points}
+}
+
+func (r *ring) at(i int) *Point { return &r.points[(r.start+i)%len(r.points)] }
+
+func (r *ring) add(p Point) {
+	if r.resolution > 0 {
+		p.Time = p.Time.Truncate(r.resolution)
+		if r.n > 0 {
+			if last := r.at(r.n - 1); last.Time.Equal(p.Time) {
+				last.merge(p)
+				return
+			}
+		}
+	}
+	if r.n < r.size {
+		if len(r.points) == cap(r.points) {
+			r.points = slices.Grow(r.points, min(max(r.n, 8), r.size-r.n))
+		}
+		r.points = append(r.points, p)
+		r.n++
+		return
+	}
+	r.points[r.start] = p
+	r.start = (r.start + 1) % len(r.points)
+}
+
+// holds reports whether the ring has every point since t, because it has
+// not wrapped around yet or its oldest point is no later than t.
+func (r *ring) holds(t time.Time) bool {
+	return r.n < r.size || !r.at(0).Time.After(t)
+}
+
+func (r *ring) between(from, to time.Time) []Point {
+	var points []Point
+	for i := 0; i < r.n; i++ {
+		p := r.at(i)
+		if p.Time.Before(from) || p.Time.After(to) {
+			continue
+		}
+		points = append(points, *p)
+	}
+	return points
+}
@@ -0,0 +1,258 @@
+// Package httpmetrics instruments net/http servers and clients with metrics
+// kept in a reporter.Registry.
+package httpmetrics
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/someview/go-metrics/clock"
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/internal/instrument"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/someview/go-metrics/sample"
+)
+
+// UnmatchedRoute is the route of requests the default route function cannot
+// attribute to a ServeMux pattern, e.g. those answered with 404.
+const UnmatchedRoute = "unmatched"
+
+// Handler records metrics for every request served by the handler it wraps.
+// Metrics are named by joining the prefix, the metric, the method, the route
+// and the status class with dots, e.g.
+//
+//	http.server.requests.GET./users/{id}.2xx       counter
+//	http.server.latency.GET./users/{id}.2xx        histogram of nanoseconds
+//	http.server.request.size.GET./users/{id}.2xx   histogram of body bytes read
+//	http.server.response.size.GET./users/{id}.2xx  histogram of body bytes written
+//	http.server.inflight.GET                       gauge
+//
+// The status class is that of the final response, "2xx" to "5xx"; it is
+// "hijacked" for connections taken over with http.Hijacker, and "5xx" for
+// requests whose handler panics.  The in-flight gauge is labelled by method
+// only, as the route is known once the request has been routed.  Methods
+// other than the standard ones are labelled "OTHER".  The in-flight gauge
+// holds a count shared by every Handler recording into the registry under the
+// same prefix, and is not lost by reporters resetting gauges.
+type Handler struct {
+	options
+	next     http.Handler
+	registry reporter.Registry
+	metrics  sync.Map // labels -> *requestMetrics
+	inflight sync.Map // method -> *instrument.Level
+}
+
+// options holds the configuration shared by Handler and Transport.
+type options struct {
+	instrument.Options
+	prefix string
+	route  func(*http.Request) string
+	host   func(*http.Request) string
+}
+
+func newOptions(prefix string, opts []Option) options {
+	o := options{
+		prefix: prefix,
+		route:  PatternRoute,
+		host: func(r *http.Request) string {
+			return r.URL.Host
+		},
+		Options: instrument.DefaultOptions(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func (o *options) name(parts ...string) string {
+	if o.prefix != "" {
+		parts = append([]string{o.prefix}, parts...)
+	}
+	return strings.Join(parts, ".")
+}
+
+type labels struct {
+	method, route, class string
+}
+
+type requestMetrics struct {
+	requests     counter.Counter
+	latency      histogram.Histogram
+	requestSize  histogram.Histogram
+	responseSize histogram.Histogram
+}
+
+// Option configures a Handler or a Transport.
+type Option func(*options)
+
+// WithPrefix sets the prefix of metric names, "http.server" for a Handler and
+// "http.client" for a Transport by default.
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithRouteFunc sets the function that names the route of a request.  It is
+// called after the wrapped handler returns, so it sees whatever a router
+// stored in the request.  It should return a template such as "/users/{id}"
+// rather than the path, which would register metrics for every distinct
+// path.  By default the route is the pattern matched by an http.ServeMux, or
+// UnmatchedRoute.  Transports ignore it.
+func WithRouteFunc(f func(*http.Request) string) Option {
+	return func(o *options) {
+		o.route = f
+	}
+}
+
+// WithHostFunc sets the function that names the upstream of an outgoing
+// request, its URL's host by default.  Handlers ignore it.
+func WithHostFunc(f func(*http.Request) string) Option {
+	return func(o *options) {
+		o.host = f
+	}
+}
+
+// WithSample sets the constructor of the samples underlying the histograms,
+// sample.NewDefaultSample by default.
+func WithSample(f func() sample.Sample) Option {
+	return func(o *options) {
+		o.NewSample = f
+	}
+}
+
+// WithClock sets the clock used to measure latency.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.Clock = c
+	}
+}
+
+// NewHandler wraps next so that every request it serves is recorded in r.
+func NewHandler(r reporter.Registry, next http.Handler, opts ...Option) *Handler {
+	return &Handler{
+		options:  newOptions("http.server", opts),
+		next:     next,
+		registry: r,
+	}
+}
+
+// Middleware returns a function wrapping handlers with NewHandler.
+func Middleware(r reporter.Registry, opts ...Option) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return NewHandler(r, next, opts...)
+	}
+}
+
+// PatternRoute returns the http.ServeMux pattern that matched the request,
+// without its method and host, or UnmatchedRoute.
+func PatternRoute(r *http.Request) string {
+	pattern := r.Pattern
+	if pattern == "" {
+		return UnmatchedRoute
+	}
+	// Patterns look like "[METHOD ][HOST]/[PATH]".
+	if i := strings.IndexByte(pattern, ' '); i >= 0 {
+		pattern = strings.TrimLeft(pattern[i+1:], " \t")
+	}
+	if i := strings.IndexByte(pattern, '/'); i > 0 {
+		pattern = pattern[i:]
+	}
+	return pattern
+}
+
+// ServeHTTP serves the request with the wrapped handler and records it.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := normalizeMethod(r.Method)
+	inflight := h.inflightCount(method)
+	inflight.Add(1)
+	start := h.Clock.Now()
+
+	rw := &responseWriter{ResponseWriter: w}
+	var body *countingReader
+	if r.Body != nil && r.Body != http.NoBody {
+		body = &countingReader{ReadCloser: r.Body}
+		r.Body = body
+	}
+	completed := false
+	defer func() {
+		inflight.Add(-1)
+		class := rw.statusClass()
+		if !completed && !rw.hijacked && !rw.wroteHeader {
+			class = "5xx"
+		}
+		m := h.requestMetrics(labels{method: method, route: h.route(r), class: class})
+		m.requests.Inc(1)
+		m.latency.Update(int64(h.Clock.Now().Sub(start)))
+		var read int64
+		if body != nil {
+			read = body.n
+		}
+		m.requestSize.Update(read)
+		m.responseSize.Update(rw.written)
+	}()
+	h.next.ServeHTTP(wrapResponseWriter(rw), r)
+	completed = true
+}
+
+func (h *Handler) inflightCount(method string) *instrument.Level {
+	if l, ok := h.inflight.Load(method); ok {
+		return l.(*instrument.Level)
+	}
+	l, _ := h.inflight.LoadOrStore(method, instrument.GetOrRegisterLevel(h.registry, h.name("inflight", method)))
+	return l.(*instrument.Level)
+}
+
+func (h *Handler) requestMetrics(l labels) *requestMetrics {
+	if m, ok := h.metrics.Load(l); ok {
+		return m.(*requestMetrics)
+	}
+	m := &requestMetrics{
+		requests:     reporter.GetOrRegisterCounter(h.name("requests", l.method, l.route, l.class), h.registry),
+		latency:      h.Histogram(h.registry, h.name("latency", l.method, l.route, l.class)),
+		requestSize:  h.Histogram(h.registry, h.name("request.size", l.method, l.route, l.class)),
+		responseSize: h.Histogram(h.registry, h.name("response.size", l.method, l.route, l.class)),
+	}
+	actual, _ := h.metrics.LoadOrStore(l, m)
+	return actual.(*requestMetrics)
+}
+
+// normalizeMethod bounds the methods used in metric names to the standard
+// ones.
+func normalizeMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// countingReader counts the bytes read from a request body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// statusClass returns the class of the response status, e.g. "2xx".
+func (w *responseWriter) statusClass() string {
+	if w.hijacked {
+		return "hijacked"
+	}
+	status := w.status
+	if !w.wroteHeader {
+		status = http.StatusOK
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
@@ -0,0 +1,170 @@
+package httpmetrics
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/someview/go-metrics/clock/clocktest"
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	r := reporter.NewRegistry()
+	clk := clocktest.NewClock(time.Unix(0, 0))
+	mux := http.NewServeMux()
+	var inflight int64
+	mux.HandleFunc("POST /users/{id}", func(w http.ResponseWriter, req *http.Request) {
+		inflight = r.Get("http.server.inflight.POST").(guage.Gauge).Snapshot()
+		body, _ := io.ReadAll(req.Body)
+		clk.Add(25 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+		w.Write(append(body, body...))
+	})
+	h := NewHandler(r, mux, WithClock(clk))
+
+	for _, id := range []string{"1", "2"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/users/"+id, strings.NewReader("hello")))
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nowhere", nil))
+
+	assert.Equal(t, int64(1), inflight)
+	assert.Equal(t, int64(0), r.Get("http.server.inflight.POST").(guage.Gauge).Snapshot())
+
+	// Reporters reset gauges, which must not lose requests in flight.
+	block, release := make(chan struct{}), make(chan struct{})
+	slow := NewHandler(r, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		close(block)
+		<-release
+	}), WithPrefix("slow"))
+	go slow.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	<-block
+	g := r.Get("slow.inflight.GET").(guage.Gauge)
+	assert.Equal(t, int64(1), g.SnapShotAndReset())
+	assert.Equal(t, int64(1), g.Snapshot())
+	close(release)
+	assert.Eventually(t, func() bool { return g.Snapshot() == 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, int64(2), r.Get("http.server.requests.POST./users/{id}.2xx").(counter.Counter).Snapshot())
+	assert.Equal(t, int64(1), r.Get("http.server.requests.GET.unmatched.4xx").(counter.Counter).Snapshot())
+
+	latency := r.Get("http.server.latency.POST./users/{id}.2xx").(histogram.Histogram).Sample().Snapshot()
+	assert.Equal(t, int64(2), latency.Count())
+	assert.Equal(t, int64(25*time.Millisecond), latency.Max())
+	requestSize := r.Get("http.server.request.size.POST./users/{id}.2xx").(histogram.Histogram).Sample().Snapshot()
+	assert.Equal(t, int64(5), requestSize.Max())
+	responseSize := r.Get("http.server.response.size.POST./users/{id}.2xx").(histogram.Histogram).Sample().Snapshot()
+	assert.Equal(t, int64(10), responseSize.Max())
+}
+
+func TestHandlerSharedInflight(t *testing.T) {
+	r := reporter.NewRegistry()
+	wrap := Middleware(r)
+	block := make(chan struct{}, 2)
+	release := make(chan struct{})
+	busy := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		block <- struct{}{}
+		<-release
+	})
+	first, second := wrap(busy), wrap(busy)
+	go first.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	<-block
+	go second.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	<-block
+
+	g := r.Get("http.server.inflight.GET").(guage.Gauge)
+	assert.Equal(t, int64(2), g.Snapshot())
+	close(release)
+	assert.Eventually(t, func() bool { return g.Snapshot() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestHandlerRouteFunc(t *testing.T) {
+	r := reporter.NewRegistry()
+	h := Middleware(r, WithPrefix("api"), WithRouteFunc(func(req *http.Request) string {
+		return strings.SplitN(strings.TrimPrefix(req.URL.Path, "/"), "/", 2)[0]
+	}))(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "oops", http.StatusServiceUnavailable)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BREW", "/coffee/pot", nil))
+	assert.Equal(t, int64(1), r.Get("api.requests.OTHER.coffee.5xx").(counter.Counter).Snapshot())
+}
+
+func TestHandlerPanic(t *testing.T) {
+	r := reporter.NewRegistry()
+	h := NewHandler(r, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}))
+	assert.Panics(t, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+	assert.Equal(t, int64(0), r.Get("http.server.inflight.GET").(guage.Gauge).Snapshot())
+	assert.Equal(t, int64(1), r.Get("http.server.requests.GET.unmatched.5xx").(counter.Counter).Snapshot())
+}
+
+func TestHandlerPreservesInterfaces(t *testing.T) {
+	r := reporter.NewRegistry()
+	var canFlush, canHijack bool
+	h := NewHandler(r, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, canFlush = w.(http.Flusher)
+		_, canHijack = w.(http.Hijacker)
+		if canFlush {
+			w.(http.Flusher).Flush()
+		}
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.True(t, canFlush)
+	assert.False(t, canHijack)
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, int64(1), r.Get("http.server.requests.GET.unmatched.2xx").(counter.Counter).Snapshot())
+
+	h.ServeHTTP(struct{ http.ResponseWriter }{httptest.NewRecorder()}, httptest.NewRequest("GET", "/", nil))
+	assert.False(t, canFlush)
+}
+
+func TestHandlerHijack(t *testing.T) {
+	r := reporter.NewRegistry()
+	srv := httptest.NewServer(NewHandler(r, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 2\r\nConnection: close\r\n\r\nok")
+		buf.Flush()
+	})))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(bufio.NewReader(resp.Body))
+	resp.Body.Close()
+	assert.Equal(t, "ok", string(body))
+	assert.Eventually(t, func() bool {
+		c, ok := r.Get("http.server.requests.GET.unmatched.hijacked").(counter.Counter)
+		return ok && c.Snapshot() == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestPatternRoute(t *testing.T) {
+	for pattern, route := range map[string]string{
+		"":                           UnmatchedRoute,
+		"/":                          "/",
+		"GET /users/{id}":            "/users/{id}",
+		"example.com/static/":        "/static/",
+		"POST example.com/items/{$}": "/items/{$}",
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Pattern = pattern
+		assert.Equal(t, route, PatternRoute(req), pattern)
+	}
+}
@@ -0,0 +1,207 @@
+package httpmetrics
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/reporter"
+)
+
+// Transport is an http.RoundTripper that records metrics for every request
+// sent through the RoundTripper it wraps.  Metrics are named by joining the
+// prefix, the metric and the upstream host with dots, e.g.
+//
+//	http.client.requests.example.com:443.2xx  counter, by status class or "error"
+//	http.client.errors.example.com:443        counter of failed round trips
+//	http.client.retries.example.com:443       counter of connection retries
+//	http.client.dns.example.com:443           histogram of nanoseconds
+//	http.client.connect.example.com:443       histogram of nanoseconds
+//	http.client.tls.example.com:443           histogram of nanoseconds
+//	http.client.ttfb.example.com:443          histogram of nanoseconds
+//	http.client.latency.example.com:443       histogram of nanoseconds
+//
+// DNS, connect and TLS timings are only recorded for requests that open a
+// new connection.  Time to first byte is measured from the start of the round
+// trip to the first byte of the response headers, and latency to the end or
+// closing of the response body, whichever comes first, or to the response
+// headers of protocol switches, whose body is the upgraded connection.  A
+// retry is counted each time the wrapped transport asks for another
+// connection for the same request, which http.Transport does when a reused
+// connection fails before the request was written.
+type Transport struct {
+	options
+	base     http.RoundTripper
+	registry reporter.Registry
+	metrics  sync.Map // host -> *hostMetrics
+}
+
+type hostMetrics struct {
+	errors  counter.Counter
+	retries counter.Counter
+	dns     histogram.Histogram
+	connect histogram.Histogram
+	tls     histogram.Histogram
+	ttfb    histogram.Histogram
+	latency histogram.Histogram
+}
+
+// NewTransport wraps base, or http.DefaultTransport if it is nil, so that
+// every round trip is recorded in r.
+func NewTransport(r reporter.Registry, base http.RoundTripper, opts ...Option) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{
+		options:  newOptions("http.client", opts),
+		base:     base,
+		registry: r,
+	}
+}
+
+// RoundTrip sends the request with the wrapped RoundTripper and records it.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := t.host(req)
+	m := t.hostMetrics(host)
+	start := t.Clock.Now()
+	trace := &roundTripTrace{transport: t, metrics: m, start: start}
+	resp, err := t.base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace.clientTrace())))
+	if retries := trace.retries(); retries > 0 {
+		m.retries.Inc(retries)
+	}
+	if err != nil {
+		m.errors.Inc(1)
+		t.requests(host, "error").Inc(1)
+		m.latency.Update(int64(t.Clock.Now().Sub(start)))
+		return nil, err
+	}
+	t.requests(host, strconv.Itoa(resp.StatusCode/100)+"xx").Inc(1)
+	if resp.Body == nil || resp.Body == http.NoBody || resp.StatusCode == http.StatusSwitchingProtocols {
+		m.latency.Update(int64(t.Clock.Now().Sub(start)))
+		return resp, nil
+	}
+	resp.Body = &timedBody{ReadCloser: resp.Body, done: func() {
+		m.latency.Update(int64(t.Clock.Now().Sub(start)))
+	}}
+	return resp, nil
+}
+
+func (t *Transport) requests(host, class string) counter.Counter {
+	return reporter.GetOrRegisterCounter(t.name("requests", host, class), t.registry)
+}
+
+func (t *Transport) hostMetrics(host string) *hostMetrics {
+	if m, ok := t.metrics.Load(host); ok {
+		return m.(*hostMetrics)
+	}
+	m := &hostMetrics{
+		errors:  reporter.GetOrRegisterCounter(t.name("errors", host), t.registry),
+		retries: reporter.GetOrRegisterCounter(t.name("retries", host), t.registry),
+		dns:     t.Histogram(t.registry, t.name("dns", host)),
+		connect: t.Histogram(t.registry, t.name("connect", host)),
+		tls:     t.Histogram(t.registry, t.name("tls", host)),
+		ttfb:    t.Histogram(t.registry, t.name("ttfb", host)),
+		latency: t.Histogram(t.registry, t.name("latency", host)),
+	}
+	actual, _ := t.metrics.LoadOrStore(host, m)
+	return actual.(*hostMetrics)
+}
+
+// roundTripTrace collects the httptrace events of one round trip.  Its hooks
+// may be called concurrently, e.g. when dialing several addresses at once.
+type roundTripTrace struct {
+	mutex     sync.Mutex
+	transport *Transport
+	metrics   *hostMetrics
+	start     time.Time
+	getConns  int64
+	dnsStart  time.Time
+	tlsStart  time.Time
+	connects  map[string]time.Time
+}
+
+func (tr *roundTripTrace) clientTrace() *httptrace.ClientTrace {
+	now := tr.transport.Clock.Now
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			tr.mutex.Lock()
+			defer tr.mutex.Unlock()
+			tr.getConns++
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			tr.mutex.Lock()
+			defer tr.mutex.Unlock()
+			tr.dnsStart = now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			tr.mutex.Lock()
+			defer tr.mutex.Unlock()
+			if !tr.dnsStart.IsZero() {
+				tr.metrics.dns.Update(int64(now().Sub(tr.dnsStart)))
+			}
+		},
+		ConnectStart: func(network, addr string) {
+			tr.mutex.Lock()
+			defer tr.mutex.Unlock()
+			if tr.connects == nil {
+				tr.connects = make(map[string]time.Time)
+			}
+			tr.connects[network+" "+addr] = now()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			tr.mutex.Lock()
+			defer tr.mutex.Unlock()
+			if start, ok := tr.connects[network+" "+addr]; ok && err == nil {
+				tr.metrics.connect.Update(int64(now().Sub(start)))
+			}
+		},
+		TLSHandshakeStart: func() {
+			tr.mutex.Lock()
+			defer tr.mutex.Unlock()
+			tr.tlsStart = now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			tr.mutex.Lock()
+			defer tr.mutex.Unlock()
+			if !tr.tlsStart.IsZero() && err == nil {
+				tr.metrics.tls.Update(int64(now().Sub(tr.tlsStart)))
+			}
+		},
+		GotFirstResponseByte: func() {
+			tr.metrics.ttfb.Update(int64(now().Sub(tr.start)))
+		},
+	}
+}
+
+// retries returns the number of connections asked for beyond the first.
+func (tr *roundTripTrace) retries() int64 {
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+	return max(tr.getConns-1, 0)
+}
+
+// timedBody calls done once, when the body is read to its end or closed.
+type timedBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *timedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.done)
+	}
+	return n, err
+}
+
+func (b *timedBody) Close() error {
+	b.once.Do(b.done)
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,110 @@
+package httpmetrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func histogramCount(t *testing.T, r reporter.Registry, name string) int64 {
+	h, ok := r.Get(name).(histogram.Histogram)
+	require.True(t, ok, name)
+	return h.Sample().Snapshot().Count()
+}
+
+func TestTransportTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "hello")
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	r := reporter.NewRegistry()
+	client := &http.Client{Transport: NewTransport(r, srv.Client().Transport)}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL)
+		require.NoError(t, err)
+		if i == 0 {
+			assert.Equal(t, int64(0), histogramCount(t, r, "http.client.latency."+host), "latency waits for the body")
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "hello", string(body))
+	}
+
+	assert.Equal(t, int64(2), r.Get("http.client.requests."+host+".2xx").(counter.Counter).Snapshot())
+	assert.Equal(t, int64(1), histogramCount(t, r, "http.client.connect."+host), "the second request reuses the connection")
+	assert.Equal(t, int64(1), histogramCount(t, r, "http.client.tls."+host))
+	assert.Equal(t, int64(0), histogramCount(t, r, "http.client.dns."+host))
+	assert.Equal(t, int64(2), histogramCount(t, r, "http.client.ttfb."+host))
+	assert.Equal(t, int64(2), histogramCount(t, r, "http.client.latency."+host))
+	assert.Equal(t, int64(0), r.Get("http.client.retries."+host).(counter.Counter).Snapshot())
+}
+
+func TestTransportDNS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	u.Host = "localhost:" + u.Port()
+
+	r := reporter.NewRegistry()
+	client := &http.Client{Transport: NewTransport(r, &http.Transport{}, WithPrefix("out"))}
+	resp, err := client.Get(u.String())
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, int64(1), r.Get("out.requests."+u.Host+".5xx").(counter.Counter).Snapshot())
+	assert.Equal(t, int64(1), histogramCount(t, r, "out.dns."+u.Host))
+	assert.Equal(t, int64(1), histogramCount(t, r, "out.latency."+u.Host))
+}
+
+func TestTransportErrors(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	host := strings.TrimPrefix(srv.URL, "http://")
+	srv.Close()
+
+	r := reporter.NewRegistry()
+	client := &http.Client{Transport: NewTransport(r, &http.Transport{})}
+	_, err := client.Get(srv.URL)
+	require.Error(t, err)
+
+	assert.Equal(t, int64(1), r.Get("http.client.errors."+host).(counter.Counter).Snapshot())
+	assert.Equal(t, int64(1), r.Get("http.client.requests."+host+".error").(counter.Counter).Snapshot())
+	assert.Equal(t, int64(1), histogramCount(t, r, "http.client.latency."+host))
+}
+
+func TestTransportRetries(t *testing.T) {
+	r := reporter.NewRegistry()
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		trace := httptrace.ContextClientTrace(req.Context())
+		for i := 0; i < 3; i++ {
+			trace.GetConn(req.URL.Host)
+		}
+		return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody, Request: req}, nil
+	})
+	client := &http.Client{Transport: NewTransport(r, base, WithHostFunc(func(*http.Request) string { return "upstream" }))}
+	resp, err := client.Get("http://example.com/")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, int64(2), r.Get("http.client.retries.upstream").(counter.Counter).Snapshot())
+	assert.Equal(t, int64(1), r.Get("http.client.requests.upstream.2xx").(counter.Counter).Snapshot())
+	assert.Equal(t, int64(1), histogramCount(t, r, "http.client.latency.upstream"))
+}
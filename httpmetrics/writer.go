@@ -0,0 +1,86 @@
+package httpmetrics
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// responseWriter records the status and body size of a response.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	hijacked    bool
+	written     int64
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader && status >= 200 {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.status = http.StatusOK
+		w.wroteHeader = true
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying ResponseWriter.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+type flusher struct{ w *responseWriter }
+
+func (f flusher) Flush() {
+	if !f.w.wroteHeader {
+		f.w.status = http.StatusOK
+		f.w.wroteHeader = true
+	}
+	f.w.ResponseWriter.(http.Flusher).Flush()
+}
+
+type hijacker struct{ w *responseWriter }
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := h.w.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		h.w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// wrapResponseWriter returns a ResponseWriter that implements http.Flusher
+// and http.Hijacker exactly when the writer wrapped by w does, so handlers
+// probing for them behave as they would without instrumentation.
+func wrapResponseWriter(w *responseWriter) http.ResponseWriter {
+	_, canFlush := w.ResponseWriter.(http.Flusher)
+	_, canHijack := w.ResponseWriter.(http.Hijacker)
+	switch {
+	case canFlush && canHijack:
+		return struct {
+			*responseWriter
+			flusher
+			hijacker
+		}{w, flusher{w}, hijacker{w}}
+	case canFlush:
+		return struct {
+			*responseWriter
+			flusher
+		}{w, flusher{w}}
+	case canHijack:
+		return struct {
+			*responseWriter
+			hijacker
+		}{w, hijacker{w}}
+	}
+	return w
+}
@@ -0,0 +1,8 @@
+// Package cpu holds facts about the processor shared by the metric types
+// that lay out memory for contended updates.
+package cpu
+
+// CacheLinePadSize is the size to pad values to so that updates on different
+// cores do not bounce a shared cache line.  128 bytes also covers
+// adjacent-line prefetching.
+const CacheLinePadSize = 128
@@ -0,0 +1,91 @@
+// Package instrument holds the configuration and helpers shared by the
+// packages that instrument other code with metrics.
+package instrument
+
+import (
+	"sync/atomic"
+
+	"github.com/someview/go-metrics/clock"
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/someview/go-metrics/sample"
+)
+
+// Options are the settings every instrumentation package accepts.  Packages
+// embed them in their own options, which their WithSample and WithClock
+// options set.
+type Options struct {
+	// NewSample constructs the samples underlying histograms.
+	NewSample func() sample.Sample
+	// Clock measures durations.
+	Clock clock.Clock
+}
+
+// DefaultOptions returns Options using sample.NewDefaultSample and the
+// system clock.
+func DefaultOptions() Options {
+	return Options{
+		NewSample: sample.NewDefaultSample,
+		Clock:     clock.System(),
+	}
+}
+
+// NewHistogram constructs a histogram over a new sample.
+func (o *Options) NewHistogram() histogram.Histogram {
+	return histogram.NewHistogram(o.NewSample())
+}
+
+// Histogram returns the histogram registered in r under name, registering a
+// new one if there is none.
+func (o *Options) Histogram(r reporter.Registry, name string) histogram.Histogram {
+	return r.GetOrRegister(name, o.NewHistogram).(histogram.Histogram)
+}
+
+// Level is a gauge of a level kept by the instrumentation, such as the number
+// of requests in flight.  It reads the level instead of holding it, so that
+// reporters resetting gauges cannot lose it, and ignores Inc and Swap.
+type Level struct {
+	guage.Gauge
+	n atomic.Int64
+}
+
+func newLevel() *Level {
+	l := &Level{}
+	l.Gauge = guage.NewFunctionalGauge(l.n.Load)
+	return l
+}
+
+// Add adds delta to the level.
+func (l *Level) Add(delta int64) { l.n.Add(delta) }
+
+// Load returns the level.
+func (l *Level) Load() int64 { return l.n.Load() }
+
+// Register registers every metric of metrics in r under its name.  If one
+// cannot be registered, none is: those registered before it are unregistered
+// and its error is returned.
+func Register(r reporter.Registry, metrics map[string]interface{}) error {
+	registered := make([]string, 0, len(metrics))
+	for name, metric := range metrics {
+		if err := r.Register(name, metric); err != nil {
+			for _, name := range registered {
+				r.Unregister(name)
+			}
+			return err
+		}
+		registered = append(registered, name)
+	}
+	return nil
+}
+
+// GetOrRegisterLevel returns the Level registered in r under name,
+// registering a new one if there is none, so that every instrumented value
+// sharing the name counts toward the same gauge.  If another metric holds the
+// name, the Level returned is not registered.
+func GetOrRegisterLevel(r reporter.Registry, name string) *Level {
+	if l, ok := r.GetOrRegister(name, newLevel).(*Level); ok {
+		return l
+	}
+	return newLevel()
+}
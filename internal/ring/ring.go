@@ -0,0 +1,85 @@
+// Package ring holds the ring of time buckets behind the trailing windows of
+// histograms and SLOs.
+package ring
+
+import (
+	"math"
+	"time"
+)
+
+// never is the epoch of buckets that were never used.
+const never = math.MinInt64
+
+// Ring rotates values through n buckets of equal length covering a trailing
+// window: the value of a bucket is reset when the bucket is reused for a
+// later interval.  A window of length d therefore covers between the last
+// (n-1)d/n and d of values.  It is not safe for concurrent use.
+type Ring[T any] struct {
+	bucket int64
+	values []T
+	epochs []int64
+	reset  func(*T)
+}
+
+// New constructs a ring of n buckets covering length, with values made by
+// newValue and reset by reset.  It panics unless length is at least n
+// nanoseconds.
+func New[T any](length time.Duration, n int, newValue func() T, reset func(*T)) *Ring[T] {
+	if n <= 0 || length < time.Duration(n) {
+		panic("ring: window is shorter than its buckets")
+	}
+	r := &Ring[T]{
+		bucket: int64(length) / int64(n),
+		values: make([]T, n),
+		epochs: make([]int64, n),
+		reset:  reset,
+	}
+	for i := range r.values {
+		r.values[i] = newValue()
+		r.epochs[i] = never
+	}
+	return r
+}
+
+// epoch returns the number of the bucket interval holding now, rounding
+// down for times before the Unix epoch too.
+func (r *Ring[T]) epoch(now time.Time) int64 {
+	ns := now.UnixNano()
+	epoch := ns / r.bucket
+	if ns%r.bucket < 0 {
+		epoch--
+	}
+	return epoch
+}
+
+// At returns the value of the bucket holding now, resetting it if it held
+// an earlier interval.
+func (r *Ring[T]) At(now time.Time) *T {
+	epoch := r.epoch(now)
+	n := int64(len(r.values))
+	i := (epoch%n + n) % n
+	if r.epochs[i] != epoch {
+		r.reset(&r.values[i])
+		r.epochs[i] = epoch
+	}
+	return &r.values[i]
+}
+
+// Each calls f with the value of every bucket inside the window ending at
+// now.
+func (r *Ring[T]) Each(now time.Time, f func(*T)) {
+	oldest := r.epoch(now) - int64(len(r.values))
+	for i := range r.values {
+		if r.epochs[i] != never && r.epochs[i] > oldest {
+			f(&r.values[i])
+		}
+	}
+}
+
+// Clear resets every bucket.
+func (r *Ring[T]) Clear() {
+	for i := range r.values {
+		r.reset(&r.values[i])
+		r.epochs[i] = never
+	}
+}
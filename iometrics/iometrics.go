@@ -0,0 +1,191 @@
+// Package iometrics instruments readers, writers, network connections and
+// listeners with byte counters, latency histograms and open connection gauges
+// kept in a reporter.Registry.
+package iometrics
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/someview/go-metrics/clock"
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/internal/instrument"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/someview/go-metrics/sample"
+)
+
+// Metrics records the I/O of the readers, writers and connections it wraps.
+// Metrics are named after the prefix given to NewMetrics, e.g. "upload":
+//
+//	upload.read.bytes         counter
+//	upload.write.bytes        counter
+//	upload.read.latency       histogram of nanoseconds per read
+//	upload.write.latency      histogram of nanoseconds per write
+//	upload.connections.open   gauge, registered by the first Conn or Listener
+//
+// A WriteTo or ReadFrom call delegated to the wrapped value counts as a single
+// operation.  Several wrappers may share one Metrics to aggregate their I/O,
+// and Metrics sharing a registry and prefix share their metrics, including
+// the count of open connections.
+type Metrics struct {
+	registry     reporter.Registry
+	prefix       string
+	clock        clock.Clock
+	ReadBytes    counter.Counter
+	WriteBytes   counter.Counter
+	ReadLatency  histogram.Histogram
+	WriteLatency histogram.Histogram
+	openOnce     sync.Once
+	open         *instrument.Level
+}
+
+type options struct {
+	instrument.Options
+}
+
+// Option configures Metrics.
+type Option func(*options)
+
+// WithSample sets the constructor of the samples underlying the latency
+// histograms, sample.NewDefaultSample by default.
+func WithSample(f func() sample.Sample) Option {
+	return func(o *options) {
+		o.NewSample = f
+	}
+}
+
+// WithClock sets the clock used to measure latency.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.Clock = c
+	}
+}
+
+// NewMetrics constructs metrics kept in r under prefix.
+func NewMetrics(r reporter.Registry, prefix string, opts ...Option) *Metrics {
+	o := options{instrument.DefaultOptions()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Metrics{
+		registry:     r,
+		prefix:       prefix,
+		clock:        o.Clock,
+		ReadBytes:    reporter.GetOrRegisterCounter(prefix+".read.bytes", r),
+		WriteBytes:   reporter.GetOrRegisterCounter(prefix+".write.bytes", r),
+		ReadLatency:  o.Histogram(r, prefix+".read.latency"),
+		WriteLatency: o.Histogram(r, prefix+".write.latency"),
+	}
+}
+
+// Open returns the number of open connections wrapped by Conn or accepted by
+// a Listener under the prefix, registering the open connections gauge if it
+// is not yet.
+func (m *Metrics) Open() int64 { return m.connections().Load() }
+
+// connections returns the count of open connections, registering its gauge
+// the first time.  The count is kept by the gauge registered under the
+// prefix, so that every Metrics sharing it is reported and reporters
+// resetting gauges cannot lose connections.
+func (m *Metrics) connections() *instrument.Level {
+	m.openOnce.Do(func() {
+		m.open = instrument.GetOrRegisterLevel(m.registry, m.prefix+".connections.open")
+	})
+	return m.open
+}
+
+func (m *Metrics) opened() { m.connections().Add(1) }
+
+func (m *Metrics) closed() { m.connections().Add(-1) }
+
+func (m *Metrics) read(start time.Time, n int) {
+	m.ReadLatency.Update(int64(m.clock.Now().Sub(start)))
+	m.ReadBytes.Inc(int64(n))
+}
+
+func (m *Metrics) write(start time.Time, n int) {
+	m.WriteLatency.Update(int64(m.clock.Now().Sub(start)))
+	m.WriteBytes.Inc(int64(n))
+}
+
+// Reader wraps r.  The result implements io.WriterTo, delegating to r when r
+// does and copying through Read otherwise, as io.Copy would.
+func (m *Metrics) Reader(r io.Reader) io.Reader {
+	return &reader{r: r, m: m}
+}
+
+// Writer wraps w.  The result implements io.ReaderFrom, delegating to w when
+// w does and copying through Write otherwise, as io.Copy would.
+func (m *Metrics) Writer(w io.Writer) io.Writer {
+	return &writer{w: w, m: m}
+}
+
+// ReadWriteCloser wraps rwc.  The result implements io.WriterTo and
+// io.ReaderFrom as described for Reader and Writer.
+func (m *Metrics) ReadWriteCloser(rwc io.ReadWriteCloser) io.ReadWriteCloser {
+	return &readWriteCloser{reader: reader{r: rwc, m: m}, writer: writer{w: rwc, m: m}, c: rwc}
+}
+
+type reader struct {
+	r io.Reader
+	m *Metrics
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	start := r.m.clock.Now()
+	n, err := r.r.Read(p)
+	r.m.read(start, n)
+	return n, err
+}
+
+func (r *reader) WriteTo(w io.Writer) (int64, error) {
+	wt, ok := r.r.(io.WriterTo)
+	if !ok {
+		return io.Copy(w, readerOnly{r})
+	}
+	start := r.m.clock.Now()
+	n, err := wt.WriteTo(w)
+	r.m.ReadLatency.Update(int64(r.m.clock.Now().Sub(start)))
+	r.m.ReadBytes.Inc(n)
+	return n, err
+}
+
+type writer struct {
+	w io.Writer
+	m *Metrics
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	start := w.m.clock.Now()
+	n, err := w.w.Write(p)
+	w.m.write(start, n)
+	return n, err
+}
+
+func (w *writer) ReadFrom(r io.Reader) (int64, error) {
+	rf, ok := w.w.(io.ReaderFrom)
+	if !ok {
+		return io.Copy(writerOnly{w}, r)
+	}
+	start := w.m.clock.Now()
+	n, err := rf.ReadFrom(r)
+	w.m.WriteLatency.Update(int64(w.m.clock.Now().Sub(start)))
+	w.m.WriteBytes.Inc(n)
+	return n, err
+}
+
+type readWriteCloser struct {
+	reader
+	writer
+	c io.Closer
+}
+
+func (rwc *readWriteCloser) Close() error { return rwc.c.Close() }
+
+// readerOnly and writerOnly hide the methods io.Copy looks for, so that
+// falling back to it does not recurse.
+type readerOnly struct{ io.Reader }
+
+type writerOnly struct{ io.Writer }
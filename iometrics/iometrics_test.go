@@ -0,0 +1,152 @@
+package iometrics
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReader(t *testing.T) {
+	m := NewMetrics(reporter.NewRegistry(), "upload")
+
+	// bytes.Reader implements io.WriterTo, which is used for the whole copy.
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, m.Reader(bytes.NewReader([]byte("hello"))))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+	assert.Equal(t, int64(5), m.ReadBytes.Snapshot())
+	assert.Equal(t, int64(1), m.ReadLatency.Sample().Snapshot().Count())
+
+	// Otherwise every Read is recorded, including the one returning io.EOF.
+	buf.Reset()
+	n, err = io.Copy(&buf, m.Reader(iotest.OneByteReader(strings.NewReader("abc"))))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.Equal(t, "abc", buf.String())
+	assert.Equal(t, int64(8), m.ReadBytes.Snapshot())
+	assert.Equal(t, int64(5), m.ReadLatency.Sample().Snapshot().Count())
+	assert.Equal(t, int64(0), m.WriteBytes.Snapshot())
+}
+
+func TestWriter(t *testing.T) {
+	r := reporter.NewRegistry()
+	m := NewMetrics(r, "upload")
+
+	// bytes.Buffer implements io.ReaderFrom, which is used for the whole copy.
+	var buf bytes.Buffer
+	w := m.Writer(&buf)
+	_, err := io.Copy(w, iotest.OneByteReader(strings.NewReader("hello")))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), m.WriteBytes.Snapshot())
+	assert.Equal(t, int64(1), m.WriteLatency.Sample().Snapshot().Count())
+
+	_, err = w.Write([]byte("!"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello!", buf.String())
+	assert.Same(t, m.WriteBytes, r.Get("upload.write.bytes"))
+	assert.Equal(t, int64(6), m.WriteBytes.Snapshot())
+
+	var sb strings.Builder
+	_, err = io.Copy(m.Writer(struct{ io.Writer }{&sb}), iotest.OneByteReader(strings.NewReader("ab")))
+	require.NoError(t, err)
+	assert.Equal(t, "ab", sb.String())
+	assert.Equal(t, int64(8), m.WriteBytes.Snapshot())
+	assert.Equal(t, int64(4), m.WriteLatency.Sample().Snapshot().Count())
+}
+
+type nopCloser struct {
+	io.ReadWriter
+	closed bool
+}
+
+func (c *nopCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestReadWriteCloser(t *testing.T) {
+	m := NewMetrics(reporter.NewRegistry(), "pipe")
+	underlying := &nopCloser{ReadWriter: &bytes.Buffer{}}
+	rwc := m.ReadWriteCloser(underlying)
+	_, err := rwc.Write([]byte("ping"))
+	require.NoError(t, err)
+	p := make([]byte, 8)
+	n, err := rwc.Read(p)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(p[:n]))
+	require.NoError(t, rwc.Close())
+	assert.True(t, underlying.closed)
+	assert.Equal(t, int64(4), m.ReadBytes.Snapshot())
+	assert.Equal(t, int64(4), m.WriteBytes.Snapshot())
+	assert.Implements(t, (*io.WriterTo)(nil), rwc)
+	assert.Implements(t, (*io.ReaderFrom)(nil), rwc)
+}
+
+func TestConnAndListener(t *testing.T) {
+	r := reporter.NewRegistry()
+	server := NewMetrics(r, "proxy.server")
+	client := NewMetrics(r, "proxy.client")
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	l = server.Listener(l)
+	defer l.Close()
+
+	accepted := make(chan net.Conn)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			close(accepted)
+			return
+		}
+		io.CopyN(c, c, 5)
+		accepted <- c
+	}()
+	raw, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	c := client.Conn(raw)
+	assert.Same(t, raw, c.NetConn())
+	_, err = c.Write([]byte("hello"))
+	require.NoError(t, err)
+	p := make([]byte, 5)
+	_, err = io.ReadFull(c, p)
+	require.NoError(t, err)
+	sc := <-accepted
+	require.NotNil(t, sc)
+
+	open := r.Get("proxy.server.connections.open").(guage.Gauge)
+	assert.Equal(t, int64(1), open.SnapShotAndReset())
+	assert.Equal(t, int64(1), open.Snapshot(), "reporters cannot reset open connections")
+	assert.Equal(t, int64(1), r.Get("proxy.client.connections.open").(guage.Gauge).Snapshot())
+	assert.Equal(t, int64(5), server.ReadBytes.Snapshot())
+	assert.Equal(t, int64(5), server.WriteBytes.Snapshot())
+	assert.Equal(t, int64(5), client.WriteBytes.Snapshot())
+	assert.Equal(t, int64(5), client.ReadBytes.Snapshot())
+
+	require.NoError(t, sc.Close())
+	sc.Close()
+	require.NoError(t, c.Close())
+	assert.Equal(t, int64(0), server.Open())
+	assert.Equal(t, int64(0), client.Open())
+}
+
+func TestConnSharedPrefix(t *testing.T) {
+	r := reporter.NewRegistry()
+	a, b := NewMetrics(r, "db"), NewMetrics(r, "db")
+	c1, c2 := net.Pipe()
+	conns := []net.Conn{a.Conn(c1), b.Conn(c2)}
+
+	assert.Equal(t, int64(2), r.Get("db.connections.open").(guage.Gauge).Snapshot())
+	assert.Equal(t, int64(2), a.Open())
+	for _, c := range conns {
+		require.NoError(t, c.Close())
+	}
+	assert.Equal(t, int64(0), b.Open())
+}
@@ -0,0 +1,65 @@
+package iometrics
+
+import (
+	"io"
+	"net"
+	"sync"
+)
+
+// Conn wraps c and counts it as open until it is closed.  The result
+// implements io.ReaderFrom and io.WriterTo, delegating to c when it does, so
+// that copies between a TCP connection and a file or an unwrapped connection
+// can still use sendfile or splice.  Use NetConn to reach
+// methods of the underlying connection such as SetKeepAlive.
+func (m *Metrics) Conn(c net.Conn) *Conn {
+	m.opened()
+	return &Conn{Conn: c, reader: reader{r: c, m: m}, writer: writer{w: c, m: m}}
+}
+
+// Listener wraps l so that the connections it accepts are wrapped with Conn.
+func (m *Metrics) Listener(l net.Listener) net.Listener {
+	return &listener{Listener: l, m: m}
+}
+
+// Conn is a net.Conn wrapped by Metrics.Conn.
+type Conn struct {
+	net.Conn
+	reader reader
+	writer writer
+	once   sync.Once
+}
+
+// Read reads from the connection, recording the bytes read and the latency.
+func (c *Conn) Read(p []byte) (int, error) { return c.reader.Read(p) }
+
+// Write writes to the connection, recording the bytes written and the
+// latency.
+func (c *Conn) Write(p []byte) (int, error) { return c.writer.Write(p) }
+
+// WriteTo writes the data read from the connection to w.
+func (c *Conn) WriteTo(w io.Writer) (int64, error) { return c.reader.WriteTo(w) }
+
+// ReadFrom writes the data read from r to the connection.
+func (c *Conn) ReadFrom(r io.Reader) (int64, error) { return c.writer.ReadFrom(r) }
+
+// Close closes the connection and counts it as closed, once.
+func (c *Conn) Close() error {
+	c.once.Do(c.reader.m.closed)
+	return c.Conn.Close()
+}
+
+// NetConn returns the underlying connection.
+func (c *Conn) NetConn() net.Conn { return c.Conn }
+
+type listener struct {
+	net.Listener
+	m *Metrics
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return l.m.Conn(c), nil
+}
@@ -1,8 +1,10 @@
 package metrics
 
 import (
+	"github.com/someview/go-metrics/clock"
 	"github.com/someview/go-metrics/counter"
 	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/health"
 	"github.com/someview/go-metrics/histogram"
 	"github.com/someview/go-metrics/reporter"
 	"time"
@@ -26,9 +28,14 @@ func LogOnCue(r reporter.Registry, ch chan interface{}, l Logger) {
 // LogScaled outputs each metric in the given registry periodically using the given
 // logger. Print timings in `scale` units (eg time.Millisecond) rather than nanos.
 func LogScaled(r reporter.Registry, freq time.Duration, scale time.Duration, l Logger) {
+	LogScaledWithClock(r, freq, scale, l, clock.System())
+}
+
+// LogScaledWithClock is like LogScaled but waits for ticks of the given clock.
+func LogScaledWithClock(r reporter.Registry, freq time.Duration, scale time.Duration, l Logger, c clock.Clock) {
 	ch := make(chan interface{})
 	go func(channel chan interface{}) {
-		for _ = range time.Tick(freq) {
+		for _ = range c.NewTicker(freq).C() {
 			channel <- struct{}{}
 		}
 	}(ch)
@@ -52,19 +59,52 @@ func LogScaledOnCue(r reporter.Registry, ch chan interface{}, l Logger) {
 				l.Printf("gauge %s\n", name)
 				l.Printf("  value:       %f\n", metric.SnapshotAndReset())
 			case histogram.Histogram:
-				h := metric.Sample().SnapshotAndReset()
-				ps := h.Percentiles([]float64{0.5, 0.75, 0.95, 0.99, 0.999})
+				h := metric.Sample().SnapshotAndReset().Summary([]float64{0.5, 0.75, 0.95, 0.99, 0.999})
+				ps := h.Percentiles
+				l.Printf("histogram %s\n", name)
+				l.Printf("  count:       %9d\n", h.Count)
+				l.Printf("  min:         %9d\n", h.Min)
+				l.Printf("  max:         %9d\n", h.Max)
+				l.Printf("  mean:        %12.2f\n", h.Mean)
+				l.Printf("  stddev:      %12.2f\n", h.StdDev)
+				l.Printf("  median:      %12.2f\n", ps[0])
+				l.Printf("  75%%:         %12.2f\n", ps[1])
+				l.Printf("  95%%:         %12.2f\n", ps[2])
+				l.Printf("  99%%:         %12.2f\n", ps[3])
+				l.Printf("  99.9%%:       %12.2f\n", ps[4])
+				if windowed, ok := metric.(histogram.WindowedHistogram); ok {
+					for _, d := range windowed.Windows() {
+						w := windowed.WindowSnapshot(d).Summary([]float64{0.5, 0.99})
+						suffix := histogram.WindowName(d)
+						l.Printf("  count_%-6s %9d\n", suffix+":", w.ReqCount)
+						l.Printf("  median_%-5s %12.2f\n", suffix+":", w.Percentiles[0])
+						l.Printf("  99%%_%-8s %12.2f\n", suffix+":", w.Percentiles[1])
+					}
+				}
+			case histogram.Float64Histogram:
+				h := metric.Sample().SnapshotAndReset().Summary([]float64{0.5, 0.75, 0.95, 0.99, 0.999})
+				ps := h.Percentiles
 				l.Printf("histogram %s\n", name)
-				l.Printf("  count:       %9d\n", h.Count())
-				l.Printf("  min:         %9d\n", h.Min())
-				l.Printf("  max:         %9d\n", h.Max())
-				l.Printf("  mean:        %12.2f\n", h.Mean())
-				l.Printf("  stddev:      %12.2f\n", h.StdDev())
+				l.Printf("  count:       %9d\n", h.Count)
+				l.Printf("  min:         %12.2f\n", h.Min)
+				l.Printf("  max:         %12.2f\n", h.Max)
+				l.Printf("  mean:        %12.2f\n", h.Mean)
+				l.Printf("  stddev:      %12.2f\n", h.StdDev)
 				l.Printf("  median:      %12.2f\n", ps[0])
 				l.Printf("  75%%:         %12.2f\n", ps[1])
 				l.Printf("  95%%:         %12.2f\n", ps[2])
 				l.Printf("  99%%:         %12.2f\n", ps[3])
 				l.Printf("  99.9%%:       %12.2f\n", ps[4])
+			case health.Healthcheck:
+				status := metric.Status()
+				healthy := 0
+				if status.Healthy() {
+					healthy = 1
+				}
+				l.Printf("gauge %s\n", reporter.HealthyGaugeName(name))
+				l.Printf("  value:       %9d\n", healthy)
+				l.Printf("gauge %s\n", reporter.FailuresGaugeName(name))
+				l.Printf("  value:       %9d\n", status.ConsecutiveFailures)
 			}
 		})
 	}
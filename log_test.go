@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/someview/go-metrics/clock/clocktest"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/stretchr/testify/assert"
+)
+
+type chanLogger chan string
+
+func (l chanLogger) Printf(format string, v ...interface{}) {
+	l <- fmt.Sprintf(format, v...)
+}
+
+func TestLogScaledWithClock(t *testing.T) {
+	r := reporter.NewRegistry()
+	reporter.GetOrRegisterCounter("requests", r).Inc(3)
+	c := clocktest.NewClock(time.Now())
+	l := make(chanLogger, 2)
+	go LogScaledWithClock(r, time.Minute, time.Millisecond, l, c)
+
+	c.BlockUntil(1)
+	c.Add(59 * time.Second)
+	select {
+	case line := <-l:
+		t.Fatalf("logged before the interval elapsed: %q", line)
+	default:
+	}
+	c.Add(time.Second)
+	assert.Equal(t, "counter requests\n", <-l)
+	assert.Equal(t, "  count:               3\n", <-l)
+}
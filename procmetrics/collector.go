@@ -0,0 +1,172 @@
+// Package procmetrics exposes Linux process statistics read from /proc as
+// gauges and counters of a reporter.Registry.
+package procmetrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/someview/go-metrics/clock"
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/internal/instrument"
+	"github.com/someview/go-metrics/reporter"
+)
+
+// DefaultMaxAge is how long statistics read from /proc are reused before a
+// metric read triggers a fresh read.  It lets a reporter walk every metric of
+// the collector with a single read of each file.
+const DefaultMaxAge = time.Second
+
+// Collector reads the statistics of a process from /proc and exposes them in
+// a registry, named after WithPrefix:
+//
+//   - counters: cpu.user.milliseconds, cpu.system.milliseconds,
+//     context_switches.voluntary, context_switches.involuntary,
+//     io.read.bytes, io.write.bytes (bytes passed to read and write system
+//     calls), io.storage.read.bytes and io.storage.write.bytes
+//   - gauges: memory.resident.bytes, memory.virtual.bytes, threads,
+//     fds.open and fds.max (-1 if unlimited)
+//
+// By default statistics are read lazily when a metric is read and the last
+// read is older than DefaultMaxAge.  Run refreshes them on a schedule
+// instead.  A failed read keeps the previous values of the affected metrics.
+type Collector struct {
+	mutex    sync.Mutex
+	clock    clock.Clock
+	prefix   string
+	dir      string
+	maxAge   time.Duration
+	stats    Stats
+	lastRead time.Time
+}
+
+// Option configures a Collector.
+type Option func(*Collector)
+
+// WithPrefix sets the prefix of registered metric names, "process" by default.
+func WithPrefix(prefix string) Option {
+	return func(c *Collector) {
+		c.prefix = prefix
+	}
+}
+
+// WithProcDir sets the /proc directory of the process to collect,
+// "/proc/self" by default.
+func WithProcDir(dir string) Option {
+	return func(c *Collector) {
+		c.dir = dir
+	}
+}
+
+// WithMaxAge sets how long statistics are reused before a read triggers a
+// fresh one.  A negative age disables lazy reads, leaving refreshes to
+// Collect and Run.
+func WithMaxAge(d time.Duration) Option {
+	return func(c *Collector) {
+		c.maxAge = d
+	}
+}
+
+// WithClock sets the clock used for max age and Run's interval.
+func WithClock(clk clock.Clock) Option {
+	return func(c *Collector) {
+		c.clock = clk
+	}
+}
+
+// NewCollector constructs a collector.  Call Register to expose its metrics.
+func NewCollector(opts ...Option) *Collector {
+	c := &Collector{
+		clock:  clock.System(),
+		prefix: "process",
+		dir:    "/proc/self",
+		maxAge: DefaultMaxAge,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Register reads the statistics once and registers a gauge or counter for
+// each of them.  It returns the read error, if any, after registering.  If
+// one cannot be registered, none is and the registration error is returned.
+func (c *Collector) Register(r reporter.Registry) error {
+	readErr := c.Collect()
+	counters := map[string]func(Stats) int64{
+		"cpu.user.milliseconds":        func(s Stats) int64 { return s.CPUUser.Milliseconds() },
+		"cpu.system.milliseconds":      func(s Stats) int64 { return s.CPUSystem.Milliseconds() },
+		"context_switches.voluntary":   func(s Stats) int64 { return s.VoluntaryCtxSwitches },
+		"context_switches.involuntary": func(s Stats) int64 { return s.InvoluntaryCtxSwitches },
+		"io.read.bytes":                func(s Stats) int64 { return s.ReadChars },
+		"io.write.bytes":               func(s Stats) int64 { return s.WriteChars },
+		"io.storage.read.bytes":        func(s Stats) int64 { return s.ReadBytes },
+		"io.storage.write.bytes":       func(s Stats) int64 { return s.WriteBytes },
+	}
+	gauges := map[string]func(Stats) int64{
+		"memory.resident.bytes": func(s Stats) int64 { return s.ResidentBytes },
+		"memory.virtual.bytes":  func(s Stats) int64 { return s.VirtualBytes },
+		"threads":               func(s Stats) int64 { return s.Threads },
+		"fds.open":              func(s Stats) int64 { return s.OpenFDs },
+		"fds.max":               func(s Stats) int64 { return s.MaxFDs },
+	}
+	metrics := make(map[string]interface{}, len(counters)+len(gauges))
+	for name, f := range counters {
+		metrics[c.name(name)] = counter.NewFunctionalCounter(func() int64 { return f(c.Stats()) })
+	}
+	for name, f := range gauges {
+		metrics[c.name(name)] = guage.NewFunctionalGauge(func() int64 { return f(c.Stats()) })
+	}
+	if err := instrument.Register(r, metrics); err != nil {
+		return err
+	}
+	return readErr
+}
+
+func (c *Collector) name(name string) string {
+	if c.prefix == "" {
+		return name
+	}
+	return c.prefix + "." + name
+}
+
+// Collect reads the statistics from /proc.  Statistics whose file could not
+// be read keep their previous values.
+func (c *Collector) Collect() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.collect()
+}
+
+func (c *Collector) collect() error {
+	err := readStats(c.dir, &c.stats)
+	c.lastRead = c.clock.Now()
+	return err
+}
+
+// Stats returns the last statistics read, reading them afresh if they are
+// stale.
+func (c *Collector) Stats() Stats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.maxAge >= 0 && c.clock.Now().Sub(c.lastRead) > c.maxAge {
+		c.collect()
+	}
+	return c.stats
+}
+
+// Run calls Collect every interval until ctx is done.
+func (c *Collector) Run(ctx context.Context, interval time.Duration) {
+	ticker := c.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			c.Collect()
+		}
+	}
+}
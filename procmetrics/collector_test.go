@@ -0,0 +1,136 @@
+package procmetrics
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/someview/go-metrics/clock/clocktest"
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadStats(t *testing.T) {
+	s, err := ReadStats("testdata/proc")
+	require.NoError(t, err)
+	assert.Equal(t, Stats{
+		CPUUser:                12340 * time.Millisecond,
+		CPUSystem:              5670 * time.Millisecond,
+		ResidentBytes:          20480 * 1024,
+		VirtualBytes:           1078272 * 1024,
+		Threads:                9,
+		VoluntaryCtxSwitches:   1500,
+		InvoluntaryCtxSwitches: 42,
+		ReadChars:              1048576,
+		WriteChars:             524288,
+		ReadBytes:              8192,
+		WriteBytes:             4096,
+		OpenFDs:                5,
+		MaxFDs:                 1024,
+	}, s)
+}
+
+func TestReadStatsMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile("testdata/proc/status")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "status"), data, 0o644))
+
+	s, err := ReadStats(dir)
+	assert.Error(t, err)
+	assert.Equal(t, int64(9), s.Threads)
+	assert.Zero(t, s.CPUUser)
+	assert.Zero(t, s.OpenFDs)
+}
+
+func TestParseErrors(t *testing.T) {
+	var s Stats
+	assert.Error(t, parseStat(strings.NewReader("42 cat R 1 2"), &s))
+	assert.Error(t, parseStat(strings.NewReader("42 (cat) R 1 2"), &s))
+	assert.Error(t, parseStatus(strings.NewReader("VmRSS:\tlots kB\n"), &s))
+	assert.Error(t, parseIO(strings.NewReader("rchar: -\n"), &s))
+	assert.Error(t, parseLimits(strings.NewReader("Max cpu time  unlimited  unlimited  seconds\n"), &s))
+
+	require.NoError(t, parseLimits(strings.NewReader("Max open files  unlimited  unlimited  files\n"), &s))
+	assert.Equal(t, int64(-1), s.MaxFDs)
+}
+
+func TestCollectorRegister(t *testing.T) {
+	r := reporter.NewRegistry()
+	c := NewCollector(WithProcDir("testdata/proc"), WithPrefix("proc"))
+	require.NoError(t, c.Register(r))
+
+	assert.Equal(t, int64(12340), r.Get("proc.cpu.user.milliseconds").(counter.Counter).Snapshot())
+	assert.Equal(t, int64(42), r.Get("proc.context_switches.involuntary").(counter.Counter).Snapshot())
+	assert.Equal(t, int64(4096), r.Get("proc.io.storage.write.bytes").(counter.Counter).Snapshot())
+	assert.Equal(t, int64(1048576), r.Get("proc.io.read.bytes").(counter.Counter).Snapshot())
+	assert.Equal(t, int64(20480*1024), r.Get("proc.memory.resident.bytes").(guage.Gauge).SnapShotAndReset())
+	assert.Equal(t, int64(9), r.Get("proc.threads").(guage.Gauge).SnapShotAndReset())
+	assert.Equal(t, int64(5), r.Get("proc.fds.open").(guage.Gauge).Snapshot())
+	assert.Equal(t, int64(1024), r.Get("proc.fds.max").(guage.Gauge).Snapshot())
+}
+
+func TestCollectorRegisterConflict(t *testing.T) {
+	r := reporter.NewRegistry()
+	require.NoError(t, r.Register("proc.threads", counter.NewCounter()))
+	c := NewCollector(WithProcDir("testdata/proc"), WithPrefix("proc"))
+	assert.Error(t, c.Register(r))
+	assert.Nil(t, r.Get("proc.fds.open"))
+	assert.Nil(t, r.Get("proc.cpu.user.milliseconds"))
+}
+
+func TestCollectorKeepsValuesOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"stat", "status", "io", "limits"} {
+		data, err := os.ReadFile(filepath.Join("testdata/proc", name))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o644))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "fd"), 0o755))
+
+	clk := clocktest.NewClock(time.Unix(0, 0))
+	r := reporter.NewRegistry()
+	c := NewCollector(WithProcDir(dir), WithClock(clk))
+	require.NoError(t, c.Register(r))
+	threads := r.Get("process.threads").(guage.Gauge)
+	assert.Equal(t, int64(9), threads.Snapshot())
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "status")))
+	clk.Add(DefaultMaxAge + time.Millisecond)
+	assert.Equal(t, int64(9), threads.Snapshot())
+	assert.Error(t, c.Collect())
+}
+
+func TestCollectorSelf(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("/proc is only available on Linux")
+	}
+	r := reporter.NewRegistry()
+	c := NewCollector()
+	require.NoError(t, c.Register(r))
+	assert.Positive(t, r.Get("process.memory.resident.bytes").(guage.Gauge).Snapshot())
+	assert.Positive(t, r.Get("process.threads").(guage.Gauge).Snapshot())
+	assert.Positive(t, r.Get("process.fds.open").(guage.Gauge).Snapshot())
+	assert.NotZero(t, r.Get("process.fds.max").(guage.Gauge).Snapshot())
+}
+
+func TestReadStatsSelfFDs(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("/proc is only available on Linux")
+	}
+	before, _ := ReadStats("/proc/self")
+	f, err := os.Open("testdata/proc/stat")
+	require.NoError(t, err)
+	defer f.Close()
+	after, _ := ReadStats("/proc/self")
+	entries, err := os.ReadDir("/proc/self/fd")
+	require.NoError(t, err)
+	assert.Equal(t, before.OpenFDs+1, after.OpenFDs)
+	assert.Equal(t, int64(len(entries))-1, after.OpenFDs, "the fd listing the directory is not counted")
+}
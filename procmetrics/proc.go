@@ -0,0 +1,249 @@
+package procmetrics
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// userHZ is the unit of CPU times in /proc/<pid>/stat.  The kernel reports
+// them in USER_HZ, which is 100 on every Linux architecture Go supports.
+const userHZ = 100
+
+// Stats holds the process statistics read from a /proc/<pid> directory.
+// Cumulative values such as CPU time, context switches and I/O count from the
+// start of the process.
+type Stats struct {
+	// CPUUser and CPUSystem are the CPU time spent in user and kernel mode,
+	// from stat.
+	CPUUser   time.Duration
+	CPUSystem time.Duration
+	// ResidentBytes, VirtualBytes and Threads are VmRSS, VmSize and Threads
+	// from status.
+	ResidentBytes int64
+	VirtualBytes  int64
+	Threads       int64
+	// VoluntaryCtxSwitches and InvoluntaryCtxSwitches are the context
+	// switches from status.
+	VoluntaryCtxSwitches   int64
+	InvoluntaryCtxSwitches int64
+	// ReadChars and WriteChars are the bytes passed to read and write system
+	// calls, and ReadBytes and WriteBytes the bytes fetched from or sent to
+	// storage, from io.
+	ReadChars  int64
+	WriteChars int64
+	ReadBytes  int64
+	WriteBytes int64
+	// OpenFDs is the number of entries in fd, less the one ReadStats opens
+	// to list them, and MaxFDs the soft limit on open files from limits, or
+	// -1 if it is unlimited.
+	OpenFDs int64
+	MaxFDs  int64
+}
+
+// ReadStats reads the statistics of the process whose /proc directory is dir,
+// e.g. "/proc/self".  Every file is read even if another fails; the returned
+// error joins the failures and the fields of failed files are left zero.
+func ReadStats(dir string) (Stats, error) {
+	var s Stats
+	err := readStats(dir, &s)
+	return s, err
+}
+
+// readStats updates s with the statistics read from dir, leaving the fields
+// of files that could not be read as they are.
+func readStats(dir string, s *Stats) error {
+	var errs []error
+	for _, f := range []struct {
+		name  string
+		parse func(io.Reader, *Stats) error
+	}{
+		{"stat", parseStat},
+		{"status", parseStatus},
+		{"io", parseIO},
+		{"limits", parseLimits},
+	} {
+		if err := parseFile(filepath.Join(dir, f.name), s, f.parse); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if n, err := countFDs(filepath.Join(dir, "fd")); err != nil {
+		errs = append(errs, err)
+	} else {
+		s.OpenFDs = n
+	}
+	return errors.Join(errs...)
+}
+
+// countFDs counts the entries of an fd directory.  Listing the fds of this
+// process lists the one opened to read the directory too, which is not
+// counted.
+func countFDs(dir string) (int64, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return 0, err
+	}
+	n := int64(len(names))
+	own := filepath.Join(dir, strconv.FormatUint(uint64(f.Fd()), 10))
+	if target, err := os.Readlink(own); err == nil {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil && target == resolved {
+			n--
+		}
+	}
+	return n, nil
+}
+
+func parseFile(path string, s *Stats, parse func(io.Reader, *Stats) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := parse(f, s); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// parseStat parses the CPU times of /proc/<pid>/stat.  The command name in
+// the second field may contain spaces and parentheses, so fields are counted
+// from the last closing parenthesis.
+func parseStat(r io.Reader, s *Stats) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	i := bytes.LastIndexByte(data, ')')
+	if i < 0 {
+		return errors.New("missing command name")
+	}
+	// fields[0] is the state, the third field of the file.
+	fields := strings.Fields(string(data[i+1:]))
+	if len(fields) < 13 {
+		return fmt.Errorf("%d fields after command name", len(fields))
+	}
+	utime, err := strconv.ParseInt(fields[11], 10, 64)
+	if err != nil {
+		return err
+	}
+	stime, err := strconv.ParseInt(fields[12], 10, 64)
+	if err != nil {
+		return err
+	}
+	s.CPUUser = time.Duration(utime) * time.Second / userHZ
+	s.CPUSystem = time.Duration(stime) * time.Second / userHZ
+	return nil
+}
+
+// parseStatus parses the memory, thread and context switch lines of
+// /proc/<pid>/status.
+func parseStatus(r io.Reader, s *Stats) error {
+	return parseKeyValues(r, func(key, value string) error {
+		var dst *int64
+		scale := int64(1)
+		switch key {
+		case "VmRSS":
+			dst, scale = &s.ResidentBytes, 1024
+		case "VmSize":
+			dst, scale = &s.VirtualBytes, 1024
+		case "Threads":
+			dst = &s.Threads
+		case "voluntary_ctxt_switches":
+			dst = &s.VoluntaryCtxSwitches
+		case "nonvoluntary_ctxt_switches":
+			dst = &s.InvoluntaryCtxSwitches
+		default:
+			return nil
+		}
+		n, err := strconv.ParseInt(strings.TrimSuffix(value, " kB"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		*dst = n * scale
+		return nil
+	})
+}
+
+// parseIO parses /proc/<pid>/io.
+func parseIO(r io.Reader, s *Stats) error {
+	return parseKeyValues(r, func(key, value string) error {
+		var dst *int64
+		switch key {
+		case "rchar":
+			dst = &s.ReadChars
+		case "wchar":
+			dst = &s.WriteChars
+		case "read_bytes":
+			dst = &s.ReadBytes
+		case "write_bytes":
+			dst = &s.WriteBytes
+		default:
+			return nil
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		*dst = n
+		return nil
+	})
+}
+
+// parseLimits parses the soft limit on open files of /proc/<pid>/limits,
+// whose columns are aligned with spaces.
+func parseLimits(r io.Reader, s *Stats) error {
+	const prefix = "Max open files"
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		fields := strings.Fields(line[len(prefix):])
+		if len(fields) == 0 {
+			return errors.New("missing open files limit")
+		}
+		if fields[0] == "unlimited" {
+			s.MaxFDs = -1
+			return nil
+		}
+		n, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("open files limit: %w", err)
+		}
+		s.MaxFDs = n
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("missing open files limit")
+}
+
+// parseKeyValues calls f with each "key: value" line, the value trimmed of
+// surrounding whitespace.
+func parseKeyValues(r io.Reader, f func(key, value string) error) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		if err := f(key, strings.TrimSpace(value)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,22 @@
+// Package queuemetrics observes channels and runs instrumented worker pools
+// with metrics kept in a reporter.Registry.
+package queuemetrics
+
+import (
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/reporter"
+)
+
+// RegisterChannel registers gauges named name+".len" and name+".cap" that
+// read the number of elements queued in ch and its capacity each time they
+// are reported.  If either cannot be registered, neither is.
+func RegisterChannel[T any](r reporter.Registry, name string, ch <-chan T) error {
+	if err := r.Register(name+".len", guage.NewFunctionalGauge(func() int64 { return int64(len(ch)) })); err != nil {
+		return err
+	}
+	if err := r.Register(name+".cap", guage.NewFunctionalGauge(func() int64 { return int64(cap(ch)) })); err != nil {
+		r.Unregister(name + ".len")
+		return err
+	}
+	return nil
+}
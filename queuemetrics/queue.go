@@ -0,0 +1,197 @@
+package queuemetrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/someview/go-metrics/clock"
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/internal/instrument"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/someview/go-metrics/sample"
+)
+
+var (
+	// ErrQueueFull is returned by TrySubmit when the queue has no room.
+	ErrQueueFull = errors.New("queuemetrics: queue is full")
+	// ErrQueueClosed is returned when submitting to a closed queue.
+	ErrQueueClosed = errors.New("queuemetrics: queue is closed")
+)
+
+// Queue is a bounded job queue served by a fixed number of workers.  Its
+// metrics are named after the name given to NewQueue, e.g. "thumbnails":
+//
+//	thumbnails.wait            histogram of nanoseconds from submission to start
+//	thumbnails.exec            histogram of nanoseconds spent running jobs
+//	thumbnails.rejected        counter of jobs that could not be submitted
+//	thumbnails.workers.active  gauge of workers running a job
+//	thumbnails.workers.total   gauge of workers
+//	thumbnails.queue.len       gauge of jobs waiting, see RegisterChannel
+//	thumbnails.queue.cap       gauge of the queue's capacity
+type Queue struct {
+	mutex    sync.RWMutex
+	closed   bool
+	jobs     chan job
+	clock    clock.Clock
+	wait     histogram.Histogram
+	exec     histogram.Histogram
+	rejected counter.Counter
+	active   atomic.Int64
+	workers  sync.WaitGroup
+}
+
+type job struct {
+	f         func()
+	submitted time.Time
+}
+
+type options struct {
+	instrument.Options
+}
+
+// Option configures a Queue.
+type Option func(*options)
+
+// WithSample sets the constructor of the samples underlying the wait and
+// execution time histograms, sample.NewDefaultSample by default.
+func WithSample(f func() sample.Sample) Option {
+	return func(o *options) {
+		o.NewSample = f
+	}
+}
+
+// WithClock sets the clock used to measure wait and execution time.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.Clock = c
+	}
+}
+
+// NewQueue starts workers serving a queue of size jobs, whose metrics are
+// registered in r under name.  It fails without registering anything if
+// workers is not positive, size is negative or a metric is already
+// registered.
+func NewQueue(r reporter.Registry, name string, workers, size int, opts ...Option) (*Queue, error) {
+	if workers <= 0 {
+		return nil, fmt.Errorf("queuemetrics: %d workers is not positive", workers)
+	}
+	if size < 0 {
+		return nil, fmt.Errorf("queuemetrics: queue size %d is negative", size)
+	}
+	o := options{instrument.DefaultOptions()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	q := &Queue{
+		jobs:     make(chan job, size),
+		clock:    o.Clock,
+		wait:     o.NewHistogram(),
+		exec:     o.NewHistogram(),
+		rejected: counter.NewCounter(),
+	}
+	var registered []string
+	for _, m := range []struct {
+		name   string
+		metric interface{}
+	}{
+		{name + ".wait", q.wait},
+		{name + ".exec", q.exec},
+		{name + ".rejected", q.rejected},
+		{name + ".workers.active", guage.NewFunctionalGauge(q.active.Load)},
+		{name + ".workers.total", guage.NewFunctionalGauge(func() int64 { return int64(workers) })},
+	} {
+		if err := r.Register(m.name, m.metric); err != nil {
+			unregister(r, registered)
+			return nil, err
+		}
+		registered = append(registered, m.name)
+	}
+	if err := RegisterChannel(r, name+".queue", q.jobs); err != nil {
+		unregister(r, registered)
+		return nil, err
+	}
+	q.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+	return q, nil
+}
+
+func unregister(r reporter.Registry, names []string) {
+	for _, name := range names {
+		r.Unregister(name)
+	}
+}
+
+// TrySubmit queues f if there is room, and otherwise counts it as rejected
+// and returns ErrQueueFull.
+func (q *Queue) TrySubmit(f func()) error {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+	if q.closed {
+		q.rejected.Inc(1)
+		return ErrQueueClosed
+	}
+	select {
+	case q.jobs <- job{f: f, submitted: q.clock.Now()}:
+		return nil
+	default:
+		q.rejected.Inc(1)
+		return ErrQueueFull
+	}
+}
+
+// Submit queues f, waiting for room until ctx is done, in which case f is
+// counted as rejected and the context's error is returned.
+func (q *Queue) Submit(ctx context.Context, f func()) error {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+	if q.closed {
+		q.rejected.Inc(1)
+		return ErrQueueClosed
+	}
+	select {
+	case q.jobs <- job{f: f, submitted: q.clock.Now()}:
+		return nil
+	case <-ctx.Done():
+		q.rejected.Inc(1)
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting jobs and waits for the queued ones to run.  It waits
+// for Submit calls that are blocked on a full queue as well.
+func (q *Queue) Close() {
+	q.mutex.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mutex.Unlock()
+	q.workers.Wait()
+}
+
+func (q *Queue) work() {
+	defer q.workers.Done()
+	for j := range q.jobs {
+		q.run(j)
+	}
+}
+
+// run runs a job, recording it even if it panics.
+func (q *Queue) run(j job) {
+	start := q.clock.Now()
+	q.wait.Update(int64(start.Sub(j.submitted)))
+	q.active.Add(1)
+	defer func() {
+		q.active.Add(-1)
+		q.exec.Update(int64(q.clock.Now().Sub(start)))
+	}()
+	j.f()
+}
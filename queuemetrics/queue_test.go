@@ -0,0 +1,118 @@
+package queuemetrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/someview/go-metrics/clock/clocktest"
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gauge(r reporter.Registry, name string) int64 {
+	return r.Get(name).(guage.Gauge).SnapShotAndReset()
+}
+
+func TestRegisterChannel(t *testing.T) {
+	r := reporter.NewRegistry()
+	ch := make(chan int, 4)
+	require.NoError(t, RegisterChannel(r, "events", ch))
+	ch <- 1
+	ch <- 2
+	assert.Equal(t, int64(2), gauge(r, "events.len"))
+	assert.Equal(t, int64(2), gauge(r, "events.len"), "reading does not reset")
+	assert.Equal(t, int64(4), gauge(r, "events.cap"))
+	<-ch
+	assert.Equal(t, int64(1), gauge(r, "events.len"))
+	assert.Error(t, RegisterChannel(r, "events", ch))
+}
+
+func TestQueue(t *testing.T) {
+	r := reporter.NewRegistry()
+	clk := clocktest.NewClock(time.Unix(0, 0))
+	q, err := NewQueue(r, "jobs", 1, 1, WithClock(clk))
+	require.NoError(t, err)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	require.NoError(t, q.TrySubmit(func() {
+		close(started)
+		<-release
+	}))
+	<-started
+	ran := make(chan struct{})
+	require.NoError(t, q.TrySubmit(func() { close(ran) }))
+	assert.ErrorIs(t, q.TrySubmit(func() {}), ErrQueueFull)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, q.Submit(ctx, func() {}), context.Canceled)
+
+	assert.Equal(t, int64(1), gauge(r, "jobs.workers.active"))
+	assert.Equal(t, int64(1), gauge(r, "jobs.workers.total"))
+	assert.Equal(t, int64(1), gauge(r, "jobs.queue.len"))
+	assert.Equal(t, int64(1), gauge(r, "jobs.queue.cap"))
+	assert.Equal(t, int64(2), r.Get("jobs.rejected").(counter.Counter).Snapshot())
+
+	clk.Add(5 * time.Second)
+	close(release)
+	<-ran
+	q.Close()
+	assert.ErrorIs(t, q.Submit(context.Background(), func() {}), ErrQueueClosed)
+	assert.Equal(t, int64(3), r.Get("jobs.rejected").(counter.Counter).Snapshot())
+	assert.Equal(t, int64(0), gauge(r, "jobs.workers.active"))
+	assert.Equal(t, int64(0), gauge(r, "jobs.queue.len"))
+
+	wait := r.Get("jobs.wait").(histogram.Histogram).Sample().Snapshot()
+	assert.Equal(t, int64(2), wait.Count())
+	assert.Equal(t, int64(0), wait.Min())
+	assert.Equal(t, int64(5*time.Second), wait.Max())
+	exec := r.Get("jobs.exec").(histogram.Histogram).Sample().Snapshot()
+	assert.Equal(t, int64(2), exec.Count())
+	assert.Equal(t, int64(5*time.Second), exec.Max())
+}
+
+func TestQueueRunPanics(t *testing.T) {
+	r := reporter.NewRegistry()
+	q, err := NewQueue(r, "jobs", 1, 0)
+	require.NoError(t, err)
+	defer q.Close()
+
+	assert.Panics(t, func() { q.run(job{f: func() { panic("boom") }}) })
+	assert.Equal(t, int64(0), gauge(r, "jobs.workers.active"))
+	assert.Equal(t, int64(1), r.Get("jobs.exec").(histogram.Histogram).Sample().Snapshot().Count())
+}
+
+func TestQueueSubmitWaits(t *testing.T) {
+	r := reporter.NewRegistry()
+	q, err := NewQueue(r, "jobs", 2, 1)
+	require.NoError(t, err)
+	done := make(chan int, 10)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, q.Submit(context.Background(), func() { done <- i }))
+	}
+	q.Close()
+	assert.Len(t, done, 10)
+	assert.Equal(t, int64(0), r.Get("jobs.rejected").(counter.Counter).Snapshot())
+
+	_, err = NewQueue(r, "jobs", 1, 1)
+	assert.Error(t, err)
+}
+
+func TestNewQueueErrors(t *testing.T) {
+	r := reporter.NewRegistry()
+	_, err := NewQueue(r, "jobs", 0, 1)
+	assert.Error(t, err)
+	_, err = NewQueue(r, "jobs", 1, -1)
+	assert.Error(t, err)
+
+	require.NoError(t, r.Register("jobs.queue.cap", counter.NewCounter()))
+	_, err = NewQueue(r, "jobs", 1, 1)
+	assert.Error(t, err)
+	var names []string
+	r.Each(func(name string, _ interface{}) { names = append(names, name) })
+	assert.Equal(t, []string{"jobs.queue.cap"}, names, "registered metrics are unregistered")
+}
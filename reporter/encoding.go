@@ -0,0 +1,512 @@
+package reporter
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/someview/go-metrics/sample"
+)
+
+// SnapshotEncodingVersion is the version written by RegistrySnapshot's
+// MarshalBinary and MarshalJSON.  Decoders reject other versions.
+const SnapshotEncodingVersion = 1
+
+// snapshotMagic starts every binary registry snapshot.
+const snapshotMagic = "GMS"
+
+// ErrInvalidSnapshot is returned when decoding malformed snapshot data.
+var ErrInvalidSnapshot = errors.New("metrics: invalid registry snapshot encoding")
+
+// UnsupportedSnapshotVersion is the error returned when decoding a snapshot
+// written by an incompatible version of the encoder.
+type UnsupportedSnapshotVersion int
+
+func (err UnsupportedSnapshotVersion) Error() string {
+	return fmt.Sprintf("metrics: unsupported registry snapshot version: %d", int(err))
+}
+
+// Histogram sample kinds in the encodings.
+const (
+	histogramKindReservoir = "reservoir"
+	histogramKindTDigest   = "tdigest"
+)
+
+// valuesSnapshot is implemented by the reservoir snapshots of the sample
+// package, which expose their retained values.
+type valuesSnapshot interface {
+	Values() []int64
+}
+
+type float64ValuesSnapshot interface {
+	Values() []float64
+}
+
+// MarshalBinary encodes the snapshot in a compact, versioned binary format:
+// the magic "GMS", a version byte, then counters, gauges, float gauges,
+// histograms and float histograms, each as a uvarint entry count followed by
+// entries sorted by name.  Integers are varints and floats big-endian IEEE
+// 754.  Histograms carry their sample kind, request count, sample count,
+// exact statistics and retained values, or an encoded t-digest.
+func (s *RegistrySnapshot) MarshalBinary() ([]byte, error) {
+	buf := append([]byte(snapshotMagic), SnapshotEncodingVersion)
+
+	buf = binary.AppendUvarint(buf, uint64(len(s.Counters)))
+	for _, name := range sortedNames(s.Counters) {
+		buf = appendString(buf, name)
+		buf = binary.AppendVarint(buf, s.Counters[name])
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(s.Gauges)))
+	for _, name := range sortedNames(s.Gauges) {
+		buf = appendString(buf, name)
+		buf = binary.AppendVarint(buf, s.Gauges[name])
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(s.GaugeFloat64s)))
+	for _, name := range sortedNames(s.GaugeFloat64s) {
+		buf = appendString(buf, name)
+		buf = appendFloat64(buf, s.GaugeFloat64s[name])
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(s.Histograms)))
+	for _, name := range sortedNames(s.Histograms) {
+		h := s.Histograms[name]
+		buf = appendString(buf, name)
+		if d, ok := h.(*sample.TDigest); ok {
+			data, err := d.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			buf = appendString(buf, histogramKindTDigest)
+			buf = appendString(buf, string(data))
+			continue
+		}
+		v, ok := h.(valuesSnapshot)
+		if !ok {
+			return nil, fmt.Errorf("metrics: cannot encode histogram %q of type %T", name, h)
+		}
+		buf = appendString(buf, histogramKindReservoir)
+		buf = binary.AppendVarint(buf, h.Count())
+		stats := sample.SnapshotStats(h)
+		for _, x := range []int64{stats.Count, stats.Sum, stats.Min, stats.Max} {
+			buf = binary.AppendVarint(buf, x)
+		}
+		buf = binary.AppendUvarint(buf, uint64(len(v.Values())))
+		for _, x := range v.Values() {
+			buf = binary.AppendVarint(buf, x)
+		}
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(s.Float64Histograms)))
+	for _, name := range sortedNames(s.Float64Histograms) {
+		h := s.Float64Histograms[name]
+		v, ok := h.(float64ValuesSnapshot)
+		if !ok {
+			return nil, fmt.Errorf("metrics: cannot encode histogram %q of type %T", name, h)
+		}
+		buf = appendString(buf, name)
+		buf = binary.AppendVarint(buf, h.Count())
+		buf = binary.AppendVarint(buf, h.ReqCount())
+		for _, x := range []float64{h.ExactSum(), h.ExactMin(), h.ExactMax()} {
+			buf = appendFloat64(buf, x)
+		}
+		buf = binary.AppendUvarint(buf, uint64(len(v.Values())))
+		for _, x := range v.Values() {
+			buf = appendFloat64(buf, x)
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary replaces the snapshot with one encoded by MarshalBinary.
+func (s *RegistrySnapshot) UnmarshalBinary(data []byte) error {
+	if len(data) < len(snapshotMagic)+1 || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return ErrInvalidSnapshot
+	}
+	if v := data[len(snapshotMagic)]; v != SnapshotEncodingVersion {
+		return UnsupportedSnapshotVersion(v)
+	}
+	d := &decoder{data: data[len(snapshotMagic)+1:]}
+	res := NewRegistrySnapshot()
+
+	for n := d.uvarint(); n > 0 && d.err == nil; n-- {
+		res.Counters[d.string()] = d.varint()
+	}
+	for n := d.uvarint(); n > 0 && d.err == nil; n-- {
+		res.Gauges[d.string()] = d.varint()
+	}
+	for n := d.uvarint(); n > 0 && d.err == nil; n-- {
+		res.GaugeFloat64s[d.string()] = d.float64()
+	}
+
+	for n := d.uvarint(); n > 0 && d.err == nil; n-- {
+		name := d.string()
+		switch d.string() {
+		case histogramKindTDigest:
+			digest := new(sample.TDigest)
+			if err := digest.UnmarshalBinary([]byte(d.string())); err != nil && d.err == nil {
+				d.err = fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+			}
+			res.Histograms[name] = digest
+		case histogramKindReservoir:
+			count := d.varint()
+			stats := sample.Stats{Count: d.varint(), Sum: d.varint(), Min: d.varint(), Max: d.varint()}
+			if !validCounts(count, stats.Count) {
+				d.fail()
+			}
+			values := make([]int64, d.length(1))
+			for i := range values {
+				values[i] = d.varint()
+			}
+			res.Histograms[name] = sample.NewSampleSnapshotWithStats(count, values, stats)
+		default:
+			d.fail()
+		}
+	}
+
+	for n := d.uvarint(); n > 0 && d.err == nil; n-- {
+		name := d.string()
+		count := d.varint()
+		stats := sample.Float64Stats{Count: d.varint(), Sum: d.float64(), Min: d.float64(), Max: d.float64()}
+		if !validCounts(count, stats.Count) {
+			d.fail()
+		}
+		values := make([]float64, d.length(8))
+		for i := range values {
+			values[i] = d.float64()
+		}
+		res.Float64Histograms[name] = sample.NewFloat64SampleSnapshot(count, values, emptyFloat64Stats(stats))
+	}
+
+	if d.err == nil && len(d.data) != 0 {
+		d.fail()
+	}
+	if d.err != nil {
+		return d.err
+	}
+	*s = *res
+	return nil
+}
+
+// WriteTo writes the binary encoding of the snapshot to w, prefixed with its
+// length as a big-endian uint32, so that snapshots can be streamed over a
+// pipe or socket and read back with ReadRegistrySnapshot.
+func (s *RegistrySnapshot) WriteTo(w io.Writer) (int64, error) {
+	data, err := s.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	frame := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(data)), uint32(len(data)))
+	n, err := w.Write(append(frame, data...))
+	return int64(n), err
+}
+
+// ReadRegistrySnapshot reads one snapshot written by RegistrySnapshot.WriteTo.
+// The buffer grows as data arrives rather than being sized after the length
+// prefix, so a corrupt prefix cannot trigger a huge allocation.
+func ReadRegistrySnapshot(r io.Reader) (*RegistrySnapshot, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	n := int(binary.BigEndian.Uint32(header[:]))
+	data, err := io.ReadAll(io.LimitReader(r, int64(n)))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	s := new(RegistrySnapshot)
+	if err := s.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// jsonSnapshot is the JSON form of a RegistrySnapshot.
+type jsonSnapshot struct {
+	Version           int                      `json:"version"`
+	Counters          map[string]int64         `json:"counters"`
+	Gauges            map[string]int64         `json:"gauges"`
+	GaugeFloat64s     map[string]jsonFloat     `json:"gaugeFloat64s"`
+	Histograms        map[string]jsonHistogram `json:"histograms"`
+	Float64Histograms map[string]jsonHistogram `json:"float64Histograms"`
+}
+
+// jsonHistogram is the JSON form of a histogram snapshot.  Min and max are
+// omitted when no value was recorded, and null when they are not finite.  A
+// t-digest is carried in its binary encoding, which encoding/json writes as
+// base64.
+type jsonHistogram struct {
+	Kind     string      `json:"kind"`
+	ReqCount int64       `json:"reqCount"`
+	Count    int64       `json:"count"`
+	Sum      jsonFloat   `json:"sum"`
+	Min      *jsonFloat  `json:"min,omitempty"`
+	Max      *jsonFloat  `json:"max,omitempty"`
+	Values   []jsonFloat `json:"values,omitempty"`
+	Digest   []byte      `json:"digest,omitempty"`
+}
+
+// jsonFloat is a float64 encoded as null when it is NaN or infinite, which
+// JSON cannot represent, and decoded from null as NaN.
+type jsonFloat float64
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(float64(f))
+}
+
+func (f *jsonFloat) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*f = jsonFloat(math.NaN())
+		return nil
+	}
+	return json.Unmarshal(data, (*float64)(f))
+}
+
+// jsonMinMax returns the encoded min and max of a histogram, nil when no
+// value was recorded.
+func jsonMinMax(reqCount int64, min, max float64) (*jsonFloat, *jsonFloat) {
+	if reqCount == 0 {
+		return nil, nil
+	}
+	jmin, jmax := jsonFloat(min), jsonFloat(max)
+	return &jmin, &jmax
+}
+
+// decodedMinMax returns the min and max of a decoded histogram: the
+// sentinels of an empty stream when no value was recorded, and NaN for
+// those encoded as null.
+func decodedMinMax(jh jsonHistogram) (float64, float64) {
+	if jh.ReqCount == 0 {
+		return math.Inf(1), math.Inf(-1)
+	}
+	min, max := math.NaN(), math.NaN()
+	if jh.Min != nil {
+		min = float64(*jh.Min)
+	}
+	if jh.Max != nil {
+		max = float64(*jh.Max)
+	}
+	return min, max
+}
+
+// MarshalJSON encodes the snapshot as JSON.  Histogram values are exact as
+// long as they fit in a float64 mantissa.  Float values that are NaN or
+// infinite are encoded as null, and decoded as NaN.
+func (s *RegistrySnapshot) MarshalJSON() ([]byte, error) {
+	js := jsonSnapshot{
+		Version:           SnapshotEncodingVersion,
+		Counters:          s.Counters,
+		Gauges:            s.Gauges,
+		GaugeFloat64s:     make(map[string]jsonFloat, len(s.GaugeFloat64s)),
+		Histograms:        make(map[string]jsonHistogram, len(s.Histograms)),
+		Float64Histograms: make(map[string]jsonHistogram, len(s.Float64Histograms)),
+	}
+	for name, v := range s.GaugeFloat64s {
+		js.GaugeFloat64s[name] = jsonFloat(v)
+	}
+	for name, h := range s.Histograms {
+		jh := jsonHistogram{ReqCount: h.ReqCount(), Count: h.Count(), Sum: jsonFloat(h.ExactSum())}
+		jh.Min, jh.Max = jsonMinMax(h.ReqCount(), float64(h.ExactMin()), float64(h.ExactMax()))
+		switch v := h.(type) {
+		case *sample.TDigest:
+			data, err := v.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			jh.Kind, jh.Digest = histogramKindTDigest, data
+		case valuesSnapshot:
+			jh.Kind = histogramKindReservoir
+			jh.Values = make([]jsonFloat, len(v.Values()))
+			for i, x := range v.Values() {
+				jh.Values[i] = jsonFloat(x)
+			}
+		default:
+			return nil, fmt.Errorf("metrics: cannot encode histogram %q of type %T", name, h)
+		}
+		js.Histograms[name] = jh
+	}
+	for name, h := range s.Float64Histograms {
+		v, ok := h.(float64ValuesSnapshot)
+		if !ok {
+			return nil, fmt.Errorf("metrics: cannot encode histogram %q of type %T", name, h)
+		}
+		jh := jsonHistogram{
+			Kind:     histogramKindReservoir,
+			ReqCount: h.ReqCount(),
+			Count:    h.Count(),
+			Sum:      jsonFloat(h.ExactSum()),
+			Values:   make([]jsonFloat, len(v.Values())),
+		}
+		for i, x := range v.Values() {
+			jh.Values[i] = jsonFloat(x)
+		}
+		jh.Min, jh.Max = jsonMinMax(h.ReqCount(), h.ExactMin(), h.ExactMax())
+		js.Float64Histograms[name] = jh
+	}
+	return json.Marshal(js)
+}
+
+// UnmarshalJSON replaces the snapshot with one encoded by MarshalJSON.
+func (s *RegistrySnapshot) UnmarshalJSON(data []byte) error {
+	var js jsonSnapshot
+	if err := json.Unmarshal(data, &js); err != nil {
+		return err
+	}
+	if js.Version != SnapshotEncodingVersion {
+		return UnsupportedSnapshotVersion(js.Version)
+	}
+	res := NewRegistrySnapshot()
+	for name, v := range js.Counters {
+		res.Counters[name] = v
+	}
+	for name, v := range js.Gauges {
+		res.Gauges[name] = v
+	}
+	for name, v := range js.GaugeFloat64s {
+		res.GaugeFloat64s[name] = float64(v)
+	}
+	for name, jh := range js.Histograms {
+		switch jh.Kind {
+		case histogramKindTDigest:
+			digest := new(sample.TDigest)
+			if err := digest.UnmarshalBinary(jh.Digest); err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+			}
+			res.Histograms[name] = digest
+		case histogramKindReservoir:
+			if !validCounts(jh.Count, jh.ReqCount) {
+				return ErrInvalidSnapshot
+			}
+			stats := sample.Stats{Count: jh.ReqCount, Sum: int64(jh.Sum), Min: math.MaxInt64, Max: math.MinInt64}
+			if jh.Min != nil && jh.Max != nil {
+				stats.Min, stats.Max = int64(*jh.Min), int64(*jh.Max)
+			}
+			values := make([]int64, len(jh.Values))
+			for i, x := range jh.Values {
+				values[i] = int64(x)
+			}
+			res.Histograms[name] = sample.NewSampleSnapshotWithStats(jh.Count, values, stats)
+		default:
+			return ErrInvalidSnapshot
+		}
+	}
+	for name, jh := range js.Float64Histograms {
+		if jh.Kind != histogramKindReservoir || !validCounts(jh.Count, jh.ReqCount) {
+			return ErrInvalidSnapshot
+		}
+		stats := sample.Float64Stats{Count: jh.ReqCount, Sum: float64(jh.Sum)}
+		stats.Min, stats.Max = decodedMinMax(jh)
+		values := make([]float64, len(jh.Values))
+		for i, x := range jh.Values {
+			values[i] = float64(x)
+		}
+		res.Float64Histograms[name] = sample.NewFloat64SampleSnapshot(jh.Count, values, stats)
+	}
+	*s = *res
+	return nil
+}
+
+// validCounts reports whether the sample and request counts of a decoded
+// histogram are possible.
+func validCounts(count, reqCount int64) bool {
+	return count >= 0 && reqCount >= 0
+}
+
+// emptyFloat64Stats restores the sentinels of an empty stream, which
+// encode as zeros.
+func emptyFloat64Stats(s sample.Float64Stats) sample.Float64Stats {
+	if s.Count == 0 {
+		s.Min, s.Max = math.Inf(1), math.Inf(-1)
+	}
+	return s
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func appendFloat64(buf []byte, f float64) []byte {
+	return binary.BigEndian.AppendUint64(buf, math.Float64bits(f))
+}
+
+// decoder reads the primitives of the binary encoding, remembering the first
+// error so that callers can check once at the end.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = ErrInvalidSnapshot
+	}
+	d.data = nil
+}
+
+func (d *decoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+// length reads the length of a slice whose elements take at least size
+// bytes, rejecting lengths the remaining input cannot hold so that corrupt
+// data cannot trigger huge allocations.
+func (d *decoder) length(size int) int {
+	n := d.uvarint()
+	if n > uint64(len(d.data)/size) {
+		d.fail()
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) string() string {
+	n := d.length(1)
+	s := string(d.data[:n])
+	d.data = d.data[n:]
+	return s
+}
+
+func (d *decoder) float64() float64 {
+	if len(d.data) < 8 {
+		d.fail()
+		return 0
+	}
+	f := math.Float64frombits(binary.BigEndian.Uint64(d.data))
+	d.data = d.data[8:]
+	return f
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/someview/go-metrics/guage"
 	"github.com/someview/go-metrics/histogram"
 	"github.com/someview/go-metrics/sample"
+	"time"
 )
 
 // GetOrRegisterCounter returns an existing Counter or constructs and registers
@@ -42,3 +43,23 @@ func GetOrRegisterHistogram(name string, r Registry, s sample.Sample) histogram.
 	}
 	return r.GetOrRegister(name, func() histogram.Histogram { return histogram.NewHistogram(s) }).(histogram.Histogram)
 }
+
+// GetOrRegisterFloat64Histogram returns an existing Float64Histogram or
+// constructs and registers a new StandardFloat64Histogram.
+func GetOrRegisterFloat64Histogram(name string, r Registry, s sample.Float64Sample) histogram.Float64Histogram {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	return r.GetOrRegister(name, func() histogram.Float64Histogram { return histogram.NewFloat64Histogram(s) }).(histogram.Float64Histogram)
+}
+
+// GetOrRegisterWindowedHistogram returns an existing WindowedHistogram or
+// constructs and registers a new MultiWindowHistogram.
+func GetOrRegisterWindowedHistogram(name string, r Registry, s sample.Sample, newSample func() sample.Sample, windows ...time.Duration) histogram.WindowedHistogram {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	return r.GetOrRegister(name, func() histogram.WindowedHistogram {
+		return histogram.NewMultiWindowHistogram(s, newSample, windows...)
+	}).(histogram.WindowedHistogram)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/someview/go-metrics/counter"
 	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/health"
 	"github.com/someview/go-metrics/histogram"
 	"reflect"
 	"strings"
@@ -114,33 +115,7 @@ func (r *StandardRegistry) Register(name string, i interface{}) error {
 
 // GetAll metrics in the Registry
 func (r *StandardRegistry) GetAll() map[string]map[string]interface{} {
-	data := make(map[string]map[string]interface{})
-	r.Each(func(name string, i interface{}) {
-		values := make(map[string]interface{})
-		switch metric := i.(type) {
-		case counter.Counter:
-			values["count"] = metric.Snapshot()
-		case guage.Gauge:
-			values["value"] = metric.SnapShotAndReset()
-		case guage.GaugeFloat64:
-			values["value"] = metric.SnapshotAndReset()
-		case histogram.Histogram:
-			h := metric.Sample().SnapshotAndReset()
-			ps := h.Percentiles([]float64{0.5, 0.75, 0.95, 0.99, 0.999})
-			values["count"] = h.Count()
-			values["min"] = h.Min()
-			values["max"] = h.Max()
-			values["mean"] = h.Mean()
-			values["stddev"] = h.StdDev()
-			values["median"] = ps[0]
-			values["75%"] = ps[1]
-			values["95%"] = ps[2]
-			values["99%"] = ps[3]
-			values["99.9%"] = ps[4]
-		}
-		data[name] = values
-	})
-	return data
+	return SnapshotRegistry(r).Values()
 }
 
 // Unregister the metric with the given name.
@@ -164,7 +139,7 @@ func (r *StandardRegistry) register(name string, i interface{}) error {
 		return DuplicateMetric(name)
 	}
 	switch i.(type) {
-	case counter.Counter, guage.Gauge, guage.GaugeFloat64, histogram.Histogram:
+	case counter.Counter, guage.Gauge, guage.GaugeFloat64, histogram.Histogram, histogram.Float64Histogram, health.Healthcheck:
 		r.metrics[name] = i
 	}
 	return nil
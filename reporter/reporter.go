@@ -12,3 +12,9 @@ type Reporter interface {
 	IncCounter(name string, v int64)
 	ReportPeriodically(ctx context.Context, interval time.Duration)
 }
+
+// Float64HistogramUpdater is implemented by reporters that can update float64
+// histograms by name, such as the one returned by NewStdReporter.
+type Float64HistogramUpdater interface {
+	UpdateFloat64Histogram(name string, v float64)
+}
@@ -0,0 +1,232 @@
+package reporter
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strconv"
+
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/health"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/sample"
+)
+
+// AttrNames names the attributes of the records a SlogSink logs.
+type AttrNames struct {
+	// Name holds the metric's name and Value the value of counters and
+	// gauges.
+	Name  string
+	Value string
+	// Histogram is the key of the group holding a histogram's fields.  An
+	// empty key inlines them, as slog does for every empty group key.
+	Histogram string
+	// Count, Sample, Min, Max, Mean and StdDev name a histogram's fields:
+	// the number of updates, the number of values retained and their
+	// statistics.
+	Count  string
+	Sample string
+	Min    string
+	Max    string
+	Mean   string
+	StdDev string
+	// Percentile names the field of a histogram percentile.
+	Percentile func(p float64) string
+}
+
+// DefaultAttrNames are the attribute names used by NewStdReporter, with
+// histogram fields inlined next to the name.
+var DefaultAttrNames = AttrNames{
+	Name:       "name",
+	Value:      "val",
+	Count:      "count",
+	Sample:     "sample",
+	Min:        "min",
+	Max:        "max",
+	Mean:       "mean",
+	StdDev:     "stddev",
+	Percentile: PercentileAttrName,
+}
+
+// PercentileAttrName names a percentile as a percentage, e.g. "99.9%".
+func PercentileAttrName(p float64) string {
+	return strconv.FormatFloat(math.Round(p*1e6)/1e4, 'f', -1, 64) + "%"
+}
+
+// SlogSink logs metrics as structured records, one per metric, reading them
+// the way reporters do: counters are left as they are while gauges and
+// histograms are reset.  A healthcheck is logged as two gauges, named by
+// HealthyGaugeName and FailuresGaugeName.  Histogram fields are grouped with
+// slog.Group under AttrNames.Histogram, and each window of a
+// WindowedHistogram is a nested group named after it, e.g. "1m".
+type SlogSink struct {
+	logger         *slog.Logger
+	level          slog.Level
+	message        string
+	names          AttrNames
+	percentiles    []float64
+	quantileMethod sample.QuantileMethod
+}
+
+// SlogSinkOption configures a SlogSink.
+type SlogSinkOption func(*SlogSink)
+
+// WithSlogLogger sets the logger records are written to, slog.Default() at
+// the time of logging by default.
+func WithSlogLogger(l *slog.Logger) SlogSinkOption {
+	return func(s *SlogSink) {
+		s.logger = l
+	}
+}
+
+// WithSlogLevel sets the level of records, slog.LevelInfo by default.
+func WithSlogLevel(level slog.Level) SlogSinkOption {
+	return func(s *SlogSink) {
+		s.level = level
+	}
+}
+
+// WithSlogMessage sets the message of records, empty by default.
+func WithSlogMessage(msg string) SlogSinkOption {
+	return func(s *SlogSink) {
+		s.message = msg
+	}
+}
+
+// WithSlogAttrNames sets the attribute names, DefaultAttrNames by default.
+// Empty names, and a nil Percentile, are taken from DefaultAttrNames; only
+// Histogram keeps an empty name, inlining the histogram fields.
+func WithSlogAttrNames(names AttrNames) SlogSinkOption {
+	return func(s *SlogSink) {
+		s.names = names
+	}
+}
+
+// WithSlogPercentiles sets the histogram percentiles logged, 0.5, 0.95, 0.99
+// and 0.999 by default.
+func WithSlogPercentiles(ps ...float64) SlogSinkOption {
+	return func(s *SlogSink) {
+		s.percentiles = ps
+	}
+}
+
+// WithSlogQuantileMethod sets how percentiles are estimated,
+// sample.DefaultQuantile by default.
+func WithSlogQuantileMethod(m sample.QuantileMethod) SlogSinkOption {
+	return func(s *SlogSink) {
+		s.quantileMethod = m
+	}
+}
+
+// NewSlogSink constructs a SlogSink.
+func NewSlogSink(opts ...SlogSinkOption) *SlogSink {
+	s := &SlogSink{
+		level:       slog.LevelInfo,
+		names:       DefaultAttrNames,
+		percentiles: []float64{0.5, 0.95, 0.99, 0.999},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.names = s.names.withDefaults()
+	return s
+}
+
+// withDefaults fills the empty names of n, other than Histogram, and a nil
+// Percentile from DefaultAttrNames.
+func (n AttrNames) withDefaults() AttrNames {
+	d := DefaultAttrNames
+	n.Name = nameOr(n.Name, d.Name)
+	n.Value = nameOr(n.Value, d.Value)
+	n.Count = nameOr(n.Count, d.Count)
+	n.Sample = nameOr(n.Sample, d.Sample)
+	n.Min = nameOr(n.Min, d.Min)
+	n.Max = nameOr(n.Max, d.Max)
+	n.Mean = nameOr(n.Mean, d.Mean)
+	n.StdDev = nameOr(n.StdDev, d.StdDev)
+	if n.Percentile == nil {
+		n.Percentile = d.Percentile
+	}
+	return n
+}
+
+func nameOr(name, def string) string {
+	if name == "" {
+		return def
+	}
+	return name
+}
+
+// Emit logs the metric, ignoring values that are not metrics.
+func (s *SlogSink) Emit(ctx context.Context, name string, metric interface{}) {
+	s.emit(ctx, name, metric, s.quantileMethod)
+}
+
+// EmitRegistry logs every metric of the registry.
+func (s *SlogSink) EmitRegistry(ctx context.Context, r Registry) {
+	r.Each(func(name string, metric interface{}) {
+		s.Emit(ctx, name, metric)
+	})
+}
+
+func (s *SlogSink) emit(ctx context.Context, name string, metric interface{}, m sample.QuantileMethod) {
+	logger := s.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	n := s.names
+	switch instance := metric.(type) {
+	case counter.Counter:
+		logger.LogAttrs(ctx, s.level, s.message, slog.String(n.Name, name), slog.Int64(n.Value, instance.Snapshot()))
+	case guage.Gauge:
+		logger.LogAttrs(ctx, s.level, s.message, slog.String(n.Name, name), slog.Int64(n.Value, instance.SnapShotAndReset()))
+	case guage.GaugeFloat64:
+		logger.LogAttrs(ctx, s.level, s.message, slog.String(n.Name, name), slog.Float64(n.Value, instance.SnapshotAndReset()))
+	case histogram.Histogram:
+		snapshot := instance.Sample().SnapshotAndReset()
+		h := snapshot.Summary(nil)
+		attrs := []slog.Attr{
+			slog.Int64(n.Count, h.ReqCount),
+			slog.Int64(n.Sample, h.Count),
+			slog.Int64(n.Min, h.Min),
+			slog.Int64(n.Max, h.Max),
+			slog.Float64(n.Mean, h.Mean),
+			slog.Float64(n.StdDev, h.StdDev),
+		}
+		attrs = s.appendPercentiles(attrs, snapshot.PercentilesMethod(s.percentiles, m))
+		if windowed, ok := instance.(histogram.WindowedHistogram); ok {
+			for _, d := range windowed.Windows() {
+				w := windowed.WindowSnapshot(d)
+				wattrs := []slog.Attr{slog.Int64(n.Count, w.ReqCount())}
+				wattrs = s.appendPercentiles(wattrs, w.PercentilesMethod(s.percentiles, m))
+				attrs = append(attrs, slog.Attr{Key: histogram.WindowName(d), Value: slog.GroupValue(wattrs...)})
+			}
+		}
+		logger.LogAttrs(ctx, s.level, s.message, slog.String(n.Name, name), slog.Attr{Key: n.Histogram, Value: slog.GroupValue(attrs...)})
+	case histogram.Float64Histogram:
+		snapshot := instance.Sample().SnapshotAndReset()
+		h := snapshot.Summary(nil)
+		attrs := []slog.Attr{
+			slog.Int64(n.Count, h.ReqCount),
+			slog.Int64(n.Sample, h.Count),
+			slog.Float64(n.Min, h.Min),
+			slog.Float64(n.Max, h.Max),
+			slog.Float64(n.Mean, h.Mean),
+			slog.Float64(n.StdDev, h.StdDev),
+		}
+		attrs = s.appendPercentiles(attrs, snapshot.PercentilesMethod(s.percentiles, m))
+		logger.LogAttrs(ctx, s.level, s.message, slog.String(n.Name, name), slog.Attr{Key: n.Histogram, Value: slog.GroupValue(attrs...)})
+	case health.Healthcheck:
+		status := instance.Status()
+		logger.LogAttrs(ctx, s.level, s.message, slog.String(n.Name, HealthyGaugeName(name)), slog.Int64(n.Value, healthyValue(status)))
+		logger.LogAttrs(ctx, s.level, s.message, slog.String(n.Name, FailuresGaugeName(name)), slog.Int64(n.Value, status.ConsecutiveFailures))
+	}
+}
+
+func (s *SlogSink) appendPercentiles(attrs []slog.Attr, ps []float64) []slog.Attr {
+	for i, p := range s.percentiles {
+		attrs = append(attrs, slog.Float64(s.names.Percentile(p), ps[i]))
+	}
+	return attrs
+}
@@ -0,0 +1,208 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/someview/go-metrics/clock/clocktest"
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/health"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/sample"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		delete(record, "time")
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestSlogSink(t *testing.T) {
+	var buf bytes.Buffer
+	names := DefaultAttrNames
+	names.Histogram = "hist"
+	names.Value = "value"
+	sink := NewSlogSink(
+		WithSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+		WithSlogLevel(slog.LevelWarn),
+		WithSlogMessage("metric"),
+		WithSlogAttrNames(names),
+		WithSlogPercentiles(0.5, 0.999),
+	)
+
+	clk := clocktest.NewClock(time.Unix(0, 0))
+	h := histogram.NewMultiWindowHistogramWithClock(clk, sample.NewSlidingWindowSample(10),
+		func() sample.Sample { return sample.NewSlidingWindowSample(10) }, time.Minute)
+	h.Update(1)
+	h.Update(3)
+	g := guage.NewGauge()
+	g.Inc(4)
+	ctx := context.Background()
+	sink.Emit(ctx, "latency", h)
+	sink.Emit(ctx, "queue", g)
+	sink.Emit(ctx, "not a metric", 42)
+
+	assert.Equal(t, []map[string]any{
+		{
+			"level": "WARN",
+			"msg":   "metric",
+			"name":  "latency",
+			"hist": map[string]any{
+				"count": 2.0, "sample": 2.0, "min": 1.0, "max": 3.0, "mean": 2.0, "stddev": 1.0,
+				"50%": 2.0, "99.9%": 3.0,
+				"1m": map[string]any{"count": 2.0, "50%": 2.0, "99.9%": 3.0},
+			},
+		},
+		{"level": "WARN", "msg": "metric", "name": "queue", "value": 4.0},
+	}, decodeRecords(t, &buf))
+	assert.Equal(t, int64(0), g.Snapshot(), "gauges are reset")
+	assert.Equal(t, int64(0), h.Sample().Snapshot().ReqCount(), "histograms are reset")
+}
+
+func TestSlogSinkDefaults(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewSlogSink(WithSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+	r := NewRegistry()
+	r.Register("ratio", histogram.NewFloat64Histogram(sample.NewSlidingWindowFloat64Sample(10)))
+	r.Get("ratio").(histogram.Float64Histogram).Update(0.5)
+	sink.EmitRegistry(context.Background(), r)
+
+	assert.Equal(t, []map[string]any{{
+		"level": "INFO", "msg": "", "name": "ratio",
+		"count": 1.0, "sample": 1.0, "min": 0.5, "max": 0.5, "mean": 0.5, "stddev": 0.0,
+		"50%": 0.5, "95%": 0.5, "99%": 0.5, "99.9%": 0.5,
+	}}, decodeRecords(t, &buf))
+}
+
+func TestSlogSinkPartialAttrNames(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewSlogSink(
+		WithSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+		WithSlogAttrNames(AttrNames{Name: "metric", Count: "n"}),
+		WithSlogPercentiles(0.5),
+	)
+	h := histogram.NewHistogram(sample.NewSlidingWindowSample(10))
+	h.Update(2)
+	g := guage.NewGauge()
+	g.Inc(1)
+	ctx := context.Background()
+	sink.Emit(ctx, "latency", h)
+	sink.Emit(ctx, "queue", g)
+
+	assert.Equal(t, []map[string]any{
+		{
+			"level": "INFO", "msg": "", "metric": "latency",
+			"n": 1.0, "sample": 1.0, "min": 2.0, "max": 2.0, "mean": 2.0, "stddev": 0.0,
+			"50%": 2.0,
+		},
+		{"level": "INFO", "msg": "", "metric": "queue", "val": 1.0},
+	}, decodeRecords(t, &buf))
+}
+
+func TestPercentileAttrName(t *testing.T) {
+	assert.Equal(t, "50%", PercentileAttrName(0.5))
+	assert.Equal(t, "99.9%", PercentileAttrName(0.999))
+	assert.Equal(t, "99.99%", PercentileAttrName(0.9999))
+}
+
+// reportOnce runs r.ReportPeriodically for a single interval of clk.
+func reportOnce(r Reporter, clk *clocktest.Clock) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.ReportPeriodically(ctx, time.Minute)
+		close(done)
+	}()
+	clk.BlockUntil(1)
+	clk.Add(time.Minute)
+	clk.BlockUntil(1)
+	cancel()
+	<-done
+}
+
+func TestStdReporterWithSink(t *testing.T) {
+	var buf bytes.Buffer
+	c := counter.NewCounter()
+	c.Inc(2)
+	clk := clocktest.NewClock(time.Unix(0, 0))
+	r := NewStdReporter([]NamedMetric{NewCounterMetric("requests", c)},
+		WithClock(clk),
+		WithSink(NewSlogSink(WithSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil))), WithSlogMessage("report"))),
+	)
+	reportOnce(r, clk)
+
+	assert.Equal(t, []map[string]any{
+		{"level": "INFO", "msg": "report", "name": "requests", "val": 2.0},
+	}, decodeRecords(t, &buf))
+
+	assert.NotPanics(t, func() {
+		reportOnce(NewStdReporter(nil, WithClock(clk), WithSink(nil)), clk)
+	})
+}
+
+func TestStdReporterWithQuantileMethod(t *testing.T) {
+	median := func(opts ...StdReporterOption) any {
+		var buf bytes.Buffer
+		h := histogram.NewHistogram(sample.NewSlidingWindowSample(10))
+		for i := int64(1); i <= 4; i++ {
+			h.Update(i)
+		}
+		clk := clocktest.NewClock(time.Unix(0, 0))
+		sink := NewSlogSink(
+			WithSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+			WithSlogPercentiles(0.5),
+			WithSlogQuantileMethod(sample.HyndmanFan1),
+		)
+		opts = append([]StdReporterOption{WithClock(clk), WithSink(sink)}, opts...)
+		reportOnce(NewStdReporter([]NamedMetric{NewHistogramMetric("latency", h)}, opts...), clk)
+		return decodeRecords(t, &buf)[0]["50%"]
+	}
+	assert.Equal(t, 2.0, median(), "the sink's method")
+	assert.Equal(t, 2.5, median(WithQuantileMethod(sample.DefaultQuantile)))
+	assert.Equal(t, 2.5, median(WithQuantileMethod(sample.HyndmanFan7)))
+}
+
+func TestCountingHandler(t *testing.T) {
+	r := NewRegistry()
+	var buf bytes.Buffer
+	h := NewCountingHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}), r, "log.records")
+	logger := slog.New(h)
+	logger.Info("a")
+	logger.With("k", "v").WithGroup("g").Info("b")
+	logger.Debug("dropped")
+	logger.Error("c")
+	logger.Log(context.Background(), slog.LevelWarn+2, "d")
+
+	assert.Equal(t, int64(2), r.Get("log.records.info").(counter.Counter).Snapshot())
+	assert.Equal(t, int64(1), r.Get("log.records.error").(counter.Counter).Snapshot())
+	assert.Equal(t, int64(1), r.Get("log.records.warn+2").(counter.Counter).Snapshot())
+	assert.Nil(t, r.Get("log.records.debug"))
+	assert.Equal(t, 4, strings.Count(buf.String(), "\n"))
+	assert.Contains(t, buf.String(), "k=v")
+}
+
+func TestSlogSinkHealthcheck(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewSlogSink(WithSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+	h := health.NewHealthcheck(func(context.Context) error { return nil })
+	h.Check(context.Background())
+	sink.Emit(context.Background(), "db", h)
+
+	assert.Equal(t, []map[string]any{
+		{"level": "INFO", "msg": "", "name": "db.healthy", "val": 1.0},
+		{"level": "INFO", "msg": "", "name": "db.failures", "val": 0.0},
+	}, decodeRecords(t, &buf))
+}
@@ -0,0 +1,66 @@
+package reporter
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/someview/go-metrics/counter"
+)
+
+// CountingHandler is a slog.Handler that counts the records it handles per
+// level before passing them to the handler it wraps.  Counters are named by
+// joining the prefix and the lower-cased level with a dot, e.g.
+// "log.records.info" or "log.records.warn+2".  Records the wrapped handler
+// is not enabled for are not counted.
+type CountingHandler struct {
+	next     slog.Handler
+	counters *levelCounters
+}
+
+type levelCounters struct {
+	registry Registry
+	prefix   string
+	counters sync.Map // slog.Level -> counter.Counter
+}
+
+// NewCountingHandler wraps next so that the records it handles are counted
+// in r under prefix, e.g. "log.records".
+func NewCountingHandler(next slog.Handler, r Registry, prefix string) *CountingHandler {
+	return &CountingHandler{next: next, counters: &levelCounters{registry: r, prefix: prefix}}
+}
+
+// Enabled reports whether the wrapped handler handles records at level.
+func (h *CountingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle counts the record and passes it to the wrapped handler.
+func (h *CountingHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.counters.get(r.Level).Inc(1)
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs returns a CountingHandler sharing h's counters.
+func (h *CountingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &CountingHandler{next: h.next.WithAttrs(attrs), counters: h.counters}
+}
+
+// WithGroup returns a CountingHandler sharing h's counters.
+func (h *CountingHandler) WithGroup(name string) slog.Handler {
+	return &CountingHandler{next: h.next.WithGroup(name), counters: h.counters}
+}
+
+func (c *levelCounters) get(level slog.Level) counter.Counter {
+	if cnt, ok := c.counters.Load(level); ok {
+		return cnt.(counter.Counter)
+	}
+	name := strings.ToLower(level.String())
+	if c.prefix != "" {
+		name = c.prefix + "." + name
+	}
+	cnt := GetOrRegisterCounter(name, c.registry)
+	c.counters.Store(level, cnt)
+	return cnt
+}
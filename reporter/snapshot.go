@@ -0,0 +1,165 @@
+package reporter
+
+import (
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/health"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/sample"
+)
+
+// RegistrySnapshot is a point-in-time copy of every metric in a Registry.  It
+// can be encoded with MarshalBinary or MarshalJSON, shipped to an aggregator
+// process, decoded there, merged with snapshots from other workers and
+// reported.
+type RegistrySnapshot struct {
+	Counters          map[string]int64
+	Gauges            map[string]int64
+	GaugeFloat64s     map[string]float64
+	Histograms        map[string]sample.SampleSnapshot
+	Float64Histograms map[string]sample.Float64SampleSnapshot
+}
+
+// NewRegistrySnapshot constructs an empty RegistrySnapshot.
+func NewRegistrySnapshot() *RegistrySnapshot {
+	return &RegistrySnapshot{
+		Counters:          make(map[string]int64),
+		Gauges:            make(map[string]int64),
+		GaugeFloat64s:     make(map[string]float64),
+		Histograms:        make(map[string]sample.SampleSnapshot),
+		Float64Histograms: make(map[string]sample.Float64SampleSnapshot),
+	}
+}
+
+// SnapshotRegistry takes a snapshot of every metric in the registry the same
+// way reporters read them: counters are left as they are while gauges and
+// histograms are reset.  Each window of a WindowedHistogram is added as a
+// histogram named after it, e.g. "latency_5m", and is not reset.  Each
+// healthcheck is added as the gauges HealthyGaugeName and
+// FailuresGaugeName.
+//
+// Because of the resets, each metric is seen by a single snapshot.  A
+// program that both reports a registry and feeds its snapshots to something
+// else, such as an alert engine, should take one snapshot per interval and
+// pass it to each of them rather than let each take its own.
+func SnapshotRegistry(r Registry) *RegistrySnapshot {
+	s := NewRegistrySnapshot()
+	r.Each(func(name string, i interface{}) {
+		switch metric := i.(type) {
+		case counter.Counter:
+			s.Counters[name] = metric.Snapshot()
+		case guage.Gauge:
+			s.Gauges[name] = metric.SnapShotAndReset()
+		case guage.GaugeFloat64:
+			s.GaugeFloat64s[name] = metric.SnapshotAndReset()
+		case histogram.Histogram:
+			s.Histograms[name] = metric.Sample().SnapshotAndReset()
+			if windowed, ok := metric.(histogram.WindowedHistogram); ok {
+				for _, d := range windowed.Windows() {
+					s.Histograms[name+"_"+histogram.WindowName(d)] = windowed.WindowSnapshot(d)
+				}
+			}
+		case histogram.Float64Histogram:
+			s.Float64Histograms[name] = metric.Sample().SnapshotAndReset()
+		case health.Healthcheck:
+			status := metric.Status()
+			s.Gauges[HealthyGaugeName(name)] = healthyValue(status)
+			s.Gauges[FailuresGaugeName(name)] = status.ConsecutiveFailures
+		}
+	})
+	return s
+}
+
+// HealthyGaugeName names the gauge reporting whether the named healthcheck
+// is healthy, 1, or not, 0.
+func HealthyGaugeName(name string) string { return name + ".healthy" }
+
+// FailuresGaugeName names the gauge reporting the consecutive failures of
+// the named healthcheck.
+func FailuresGaugeName(name string) string { return name + ".failures" }
+
+func healthyValue(s health.Status) int64 {
+	if s.Healthy() {
+		return 1
+	}
+	return 0
+}
+
+// Merge folds other into s: counters and gauges are summed and histogram
+// snapshots are merged as described by sample.MergeSnapshots.
+func (s *RegistrySnapshot) Merge(other *RegistrySnapshot) {
+	for name, v := range other.Counters {
+		s.Counters[name] = counter.Sum(s.Counters[name], v)
+	}
+	for name, v := range other.Gauges {
+		s.Gauges[name] = guage.Aggregate(guage.AggregateSum, s.Gauges[name], v)
+	}
+	for name, v := range other.GaugeFloat64s {
+		s.GaugeFloat64s[name] = guage.AggregateFloat64(guage.AggregateSum, s.GaugeFloat64s[name], v)
+	}
+	for name, h := range other.Histograms {
+		if cur, ok := s.Histograms[name]; ok {
+			s.Histograms[name] = cur.Merge(h)
+		} else {
+			s.Histograms[name] = h
+		}
+	}
+	for name, h := range other.Float64Histograms {
+		if cur, ok := s.Float64Histograms[name]; ok {
+			s.Float64Histograms[name] = cur.Merge(h)
+		} else {
+			s.Float64Histograms[name] = h
+		}
+	}
+}
+
+// Values returns the snapshot in the format of Registry.GetAll.  The "count"
+// of a histogram is the number of values it retained, and its "reqCount"
+// the number of updates.
+func (s *RegistrySnapshot) Values() map[string]map[string]interface{} {
+	data := make(map[string]map[string]interface{})
+	for name, v := range s.Counters {
+		data[name] = map[string]interface{}{"count": v}
+	}
+	for name, v := range s.Gauges {
+		data[name] = map[string]interface{}{"value": v}
+	}
+	for name, v := range s.GaugeFloat64s {
+		data[name] = map[string]interface{}{"value": v}
+	}
+	for name, snapshot := range s.Histograms {
+		h := snapshot.Summary([]float64{0.5, 0.75, 0.95, 0.99, 0.999})
+		ps := h.Percentiles
+		data[name] = map[string]interface{}{
+			"count":    h.Count,
+			"reqCount": h.ReqCount,
+			"min":      h.Min,
+			"max":      h.Max,
+			"mean":     h.Mean,
+			"stddev":   h.StdDev,
+			"median":   ps[0],
+			"75%":      ps[1],
+			"95%":      ps[2],
+			"99%":      ps[3],
+			"99.9%":    ps[4],
+		}
+	}
+	for name, snapshot := range s.Float64Histograms {
+		h := snapshot.Summary([]float64{0.5, 0.75, 0.95, 0.99, 0.999})
+		ps := h.Percentiles
+		data[name] = map[string]interface{}{
+			"count":    h.Count,
+			"reqCount": h.ReqCount,
+			"min":      h.Min,
+			"max":      h.Max,
+			"mean":     h.Mean,
+			"stddev":   h.StdDev,
+			"median":   ps[0],
+			"75%":      ps[1],
+			"95%":      ps[2],
+			"99%":      ps[3],
+			"99.9%":    ps[4],
+		}
+	}
+	return data
+}
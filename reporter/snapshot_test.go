@@ -0,0 +1,291 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/someview/go-metrics/health"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/sample"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSnapshotTestRegistry() Registry {
+	r := NewRegistry()
+	GetOrRegisterCounter("requests", r).Inc(42)
+	GetOrRegisterGauge("inflight", r).Inc(-3)
+	GetOrRegisterGaugeFloat64("load", r).Update(0.75)
+	h := GetOrRegisterHistogram("latency", r, sample.NewSlidingWindowSample(4))
+	for i := int64(1); i <= 6; i++ {
+		h.Update(i * 10)
+	}
+	d := GetOrRegisterHistogram("payment", r, sample.NewTDigestSample(50))
+	for i := int64(0); i < 1000; i++ {
+		d.Update(i)
+	}
+	GetOrRegisterHistogram("idle", r, sample.NewSlidingWindowSample(4))
+	f := GetOrRegisterFloat64Histogram("ratio", r, sample.NewSlidingWindowFloat64Sample(4))
+	f.Update(0.25)
+	f.Update(0.5)
+	return r
+}
+
+func assertSnapshotsEqual(t *testing.T, expected, actual *RegistrySnapshot) {
+	assert.Equal(t, expected.Counters, actual.Counters)
+	assert.Equal(t, expected.Gauges, actual.Gauges)
+	assert.Equal(t, expected.GaugeFloat64s, actual.GaugeFloat64s)
+	assert.Equal(t, expected.Values(), actual.Values())
+	for name, h := range expected.Histograms {
+		a := actual.Histograms[name]
+		require.NotNil(t, a, name)
+		assert.Equal(t, h.ReqCount(), a.ReqCount(), name)
+		assert.Equal(t, h.ExactSum(), a.ExactSum(), name)
+		assert.Equal(t, h.ExactMin(), a.ExactMin(), name)
+		assert.Equal(t, h.ExactMax(), a.ExactMax(), name)
+	}
+	for name, h := range expected.Float64Histograms {
+		a := actual.Float64Histograms[name]
+		require.NotNil(t, a, name)
+		assert.Equal(t, h.ReqCount(), a.ReqCount(), name)
+		assert.Equal(t, h.ExactSum(), a.ExactSum(), name)
+		assert.Equal(t, h.ExactMax(), a.ExactMax(), name)
+	}
+}
+
+func TestRegistrySnapshot_Binary(t *testing.T) {
+	s := SnapshotRegistry(newSnapshotTestRegistry())
+	assert.Equal(t, int64(60), s.Histograms["latency"].ExactMax())
+	assert.Equal(t, int64(6), s.Histograms["latency"].ReqCount())
+	assert.Equal(t, int64(4), s.Histograms["latency"].Count())
+
+	var buf bytes.Buffer
+	_, err := s.WriteTo(&buf)
+	require.NoError(t, err)
+	_, err = s.WriteTo(&buf)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		decoded, err := ReadRegistrySnapshot(&buf)
+		require.NoError(t, err)
+		assertSnapshotsEqual(t, s, decoded)
+		_, ok := decoded.Histograms["payment"].(*sample.TDigest)
+		assert.True(t, ok)
+	}
+
+	data, err := s.MarshalBinary()
+	require.NoError(t, err)
+	var decoded RegistrySnapshot
+	assert.ErrorIs(t, decoded.UnmarshalBinary(data[:len(data)-3]), ErrInvalidSnapshot)
+	assert.ErrorIs(t, decoded.UnmarshalBinary(append(data, 0)), ErrInvalidSnapshot)
+	data[3] = 9
+	assert.Equal(t, UnsupportedSnapshotVersion(9), decoded.UnmarshalBinary(data))
+}
+
+func TestReadRegistrySnapshot_Truncated(t *testing.T) {
+	data := []byte{0xff, 0xff, 0xff, 0xff, 'G', 'M', 'S', SnapshotEncodingVersion}
+	_, err := ReadRegistrySnapshot(bytes.NewReader(data))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	_, err = ReadRegistrySnapshot(bytes.NewReader(data[:2]))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+// encodeSnapshot hand-encodes a binary snapshot from its header followed by
+// the given parts, each a uvarint, varint, string, float64 or raw bytes.
+func encodeSnapshot(parts ...any) []byte {
+	data := append([]byte(snapshotMagic), SnapshotEncodingVersion)
+	for _, part := range parts {
+		switch v := part.(type) {
+		case uint64:
+			data = binary.AppendUvarint(data, v)
+		case int64:
+			data = binary.AppendVarint(data, v)
+		case string:
+			data = appendString(data, v)
+		case float64:
+			data = appendFloat64(data, v)
+		case []byte:
+			data = append(data, v...)
+		}
+	}
+	return data
+}
+
+func TestRegistrySnapshot_UnmarshalBinaryMalformed(t *testing.T) {
+	var decoded RegistrySnapshot
+	noScalars := []any{uint64(0), uint64(0), uint64(0)}
+	histogram := func(parts ...any) []byte {
+		return encodeSnapshot(append(append(noScalars, uint64(1), "h"), parts...)...)
+	}
+	float64Histogram := func(parts ...any) []byte {
+		return encodeSnapshot(append(append(noScalars, uint64(0), uint64(1), "f"), parts...)...)
+	}
+	require.NoError(t, decoded.UnmarshalBinary(histogram(histogramKindReservoir,
+		int64(1), int64(1), int64(5), int64(5), int64(5), uint64(1), int64(5), uint64(0))))
+	assert.Equal(t, int64(5), decoded.Histograms["h"].ExactMax())
+
+	for name, data := range map[string][]byte{
+		"empty":                   nil,
+		"bad magic":               []byte("GMX\x01"),
+		"huge entry count":        encodeSnapshot(uint64(1 << 62)),
+		"huge name length":        encodeSnapshot(uint64(1), uint64(1<<62)),
+		"unknown histogram kind":  histogram("uniform"),
+		"negative sample count":   histogram(histogramKindReservoir, int64(-1), int64(0), int64(0), int64(0), int64(0), uint64(0), uint64(0)),
+		"negative request count":  histogram(histogramKindReservoir, int64(0), int64(-1), int64(0), int64(0), int64(0), uint64(0), uint64(0)),
+		"huge value count":        histogram(histogramKindReservoir, int64(0), int64(0), int64(0), int64(0), int64(0), uint64(1<<62)),
+		"malformed t-digest":      histogram(histogramKindTDigest, "\x01"),
+		"t-digest huge centroids": histogram(histogramKindTDigest, string(tdigestHeader(1<<60))),
+		"huge float value count":  float64Histogram(int64(0), int64(0), 0.0, 0.0, 0.0, uint64(2)),
+		"negative float count":    float64Histogram(int64(-1), int64(0), 0.0, 0.0, 0.0, uint64(0)),
+	} {
+		assert.ErrorIs(t, decoded.UnmarshalBinary(data), ErrInvalidSnapshot, name)
+	}
+}
+
+// tdigestHeader encodes an empty t-digest claiming n centroids.
+func tdigestHeader(n uint64) []byte {
+	data := []byte{1}
+	for _, f := range []float64{50, 0, 0, 0, 0} {
+		data = appendFloat64(data, f)
+	}
+	return binary.AppendUvarint(data, n)
+}
+
+func FuzzRegistrySnapshot_UnmarshalBinary(f *testing.F) {
+	data, err := SnapshotRegistry(newSnapshotTestRegistry()).MarshalBinary()
+	require.NoError(f, err)
+	f.Add(data)
+	f.Add(encodeSnapshot(uint64(0), uint64(0), uint64(0), uint64(1), "h", histogramKindTDigest, string(tdigestHeader(1<<60))))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var s RegistrySnapshot
+		if s.UnmarshalBinary(data) == nil {
+			readSnapshot(&s)
+		}
+	})
+}
+
+// readSnapshot reads every statistic of s, as a reporter would.
+func readSnapshot(s *RegistrySnapshot) {
+	for _, h := range s.Histograms {
+		h.Summary([]float64{0.5, 0.99})
+	}
+	for _, h := range s.Float64Histograms {
+		h.Summary([]float64{0.5, 0.99})
+	}
+}
+
+func TestRegistrySnapshot_JSON(t *testing.T) {
+	s := SnapshotRegistry(newSnapshotTestRegistry())
+	data, err := json.Marshal(s)
+	require.NoError(t, err)
+	var decoded RegistrySnapshot
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assertSnapshotsEqual(t, s, &decoded)
+
+	assert.Equal(t, UnsupportedSnapshotVersion(0), decoded.UnmarshalJSON([]byte(`{}`)))
+}
+
+func TestRegistrySnapshot_JSONNonFinite(t *testing.T) {
+	r := NewRegistry()
+	GetOrRegisterGaugeFloat64("load", r).Update(math.Inf(1))
+	GetOrRegisterGaugeFloat64("ratio", r).Update(0.5)
+	f := GetOrRegisterFloat64Histogram("spread", r, sample.NewSlidingWindowFloat64Sample(4))
+	f.Update(math.NaN())
+	f.Update(math.Inf(-1))
+	s := SnapshotRegistry(r)
+	data, err := json.Marshal(s)
+	require.NoError(t, err)
+
+	var decoded RegistrySnapshot
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.True(t, math.IsNaN(decoded.GaugeFloat64s["load"]))
+	assert.Equal(t, 0.5, decoded.GaugeFloat64s["ratio"])
+	h := decoded.Float64Histograms["spread"]
+	require.NotNil(t, h)
+	assert.Equal(t, int64(2), h.ReqCount())
+	values := h.(float64ValuesSnapshot).Values()
+	assert.Len(t, values, 2)
+	for _, v := range append(values, h.ExactSum(), h.ExactMin(), h.ExactMax()) {
+		assert.True(t, math.IsNaN(v))
+	}
+}
+
+func TestRegistrySnapshot_UnmarshalJSONMalformed(t *testing.T) {
+	var decoded RegistrySnapshot
+	for name, data := range map[string]string{
+		"unknown histogram kind": `{"version":1,"histograms":{"h":{"kind":"uniform"}}}`,
+		"negative sample count":  `{"version":1,"histograms":{"h":{"kind":"reservoir","count":-1}}}`,
+		"negative request count": `{"version":1,"histograms":{"h":{"kind":"reservoir","reqCount":-1}}}`,
+		"missing t-digest":       `{"version":1,"histograms":{"h":{"kind":"tdigest"}}}`,
+		"malformed t-digest":     `{"version":1,"histograms":{"h":{"kind":"tdigest","digest":"AQ=="}}}`,
+		"float t-digest":         `{"version":1,"float64Histograms":{"f":{"kind":"tdigest"}}}`,
+		"negative float count":   `{"version":1,"float64Histograms":{"f":{"kind":"reservoir","count":-1}}}`,
+	} {
+		assert.ErrorIs(t, decoded.UnmarshalJSON([]byte(data)), ErrInvalidSnapshot, name)
+	}
+	assert.Error(t, decoded.UnmarshalJSON([]byte(`{"version":1,"counters":[]}`)))
+}
+
+func FuzzRegistrySnapshot_UnmarshalJSON(f *testing.F) {
+	data, err := json.Marshal(SnapshotRegistry(newSnapshotTestRegistry()))
+	require.NoError(f, err)
+	f.Add(data)
+	f.Add([]byte(`{"version":1,"float64Histograms":{"f":{"kind":"reservoir","reqCount":1,"values":[1,2]}}}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var s RegistrySnapshot
+		if s.UnmarshalJSON(data) == nil {
+			readSnapshot(&s)
+		}
+	})
+}
+
+func TestRegistrySnapshot_Merge(t *testing.T) {
+	a := SnapshotRegistry(newSnapshotTestRegistry())
+	b := SnapshotRegistry(newSnapshotTestRegistry())
+	r := NewRegistry()
+	GetOrRegisterCounter("other", r).Inc(1)
+	r.GetOrRegister("latency", histogram.NewHistogram(sample.NewSlidingWindowSample(4))).(histogram.Histogram).Update(1000)
+	a.Merge(b)
+	a.Merge(SnapshotRegistry(r))
+	assert.Equal(t, int64(84), a.Counters["requests"])
+	assert.Equal(t, int64(1), a.Counters["other"])
+	assert.Equal(t, int64(-6), a.Gauges["inflight"])
+	assert.Equal(t, 1.5, a.GaugeFloat64s["load"])
+	assert.Equal(t, int64(13), a.Histograms["latency"].ReqCount())
+	assert.Equal(t, int64(1000), a.Histograms["latency"].ExactMax())
+	assert.Equal(t, int64(2000), a.Histograms["payment"].ReqCount())
+	assert.Equal(t, int64(4), a.Float64Histograms["ratio"].ReqCount())
+}
+
+func TestSnapshotRegistry_WindowedHistogram(t *testing.T) {
+	r := NewRegistry()
+	newSample := func() sample.Sample { return sample.NewSlidingWindowSample(10) }
+	h := GetOrRegisterWindowedHistogram("latency", r, sample.NewSlidingWindowSample(10), newSample, time.Minute, 5*time.Minute)
+	h.Update(250)
+	values := SnapshotRegistry(r).Values()
+	assert.Equal(t, 250.0, values["latency"]["99%"])
+	assert.Equal(t, 250.0, values["latency_1m"]["99%"])
+	assert.Equal(t, 250.0, values["latency_5m"]["99%"])
+	// Windows are not reset by reporting.
+	values = SnapshotRegistry(r).Values()
+	assert.Equal(t, int64(0), values["latency"]["count"])
+	assert.Equal(t, int64(1), values["latency_5m"]["count"])
+}
+
+func TestSnapshotRegistry_Healthcheck(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.Register("db", health.NewHealthcheck(func(context.Context) error {
+		return errors.New("down")
+	})))
+	h := r.Get("db").(health.Healthcheck)
+	h.Check(context.Background())
+	h.Check(context.Background())
+	s := SnapshotRegistry(r)
+	assert.Equal(t, map[string]int64{"db.healthy": 0, "db.failures": 2}, s.Gauges)
+}
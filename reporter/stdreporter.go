@@ -2,10 +2,12 @@ package reporter
 
 import (
 	"context"
+	"github.com/someview/go-metrics/clock"
 	"github.com/someview/go-metrics/counter"
 	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/health"
 	"github.com/someview/go-metrics/histogram"
-	"log/slog"
+	"github.com/someview/go-metrics/sample"
 	"time"
 )
 
@@ -26,6 +28,10 @@ func NewHistogramMetric(name string, m histogram.Histogram) NamedMetric {
 	return NamedMetric{name: name, m: m}
 }
 
+func NewFloat64HistogramMetric(name string, m histogram.Float64Histogram) NamedMetric {
+	return NamedMetric{name: name, m: m}
+}
+
 func NewGaugeMetric(name string, m guage.Gauge) NamedMetric {
 	return NamedMetric{name: name, m: m}
 }
@@ -38,9 +44,51 @@ func NewCounterMetric(name string, m counter.Counter) NamedMetric {
 	return NamedMetric{name: name, m: m}
 }
 
+// NewHealthcheckMetric names a healthcheck, which is reported as the gauges
+// named by HealthyGaugeName and FailuresGaugeName.
+func NewHealthcheckMetric(name string, m health.Healthcheck) NamedMetric {
+	return NamedMetric{name: name, m: m}
+}
+
 type stdReporter struct {
-	r       Registry
-	metrics []NamedMetric
+	r              Registry
+	metrics        []NamedMetric
+	clock          clock.Clock
+	quantileMethod sample.QuantileMethod
+	// quantileMethodSet tells an explicit DefaultQuantile from no method.
+	quantileMethodSet bool
+	sink              *SlogSink
+}
+
+// StdReporterOption configures a reporter constructed by NewStdReporter.
+type StdReporterOption func(*stdReporter)
+
+// WithClock makes the reporter measure reporting intervals with the given
+// clock.
+func WithClock(c clock.Clock) StdReporterOption {
+	return func(s *stdReporter) {
+		s.clock = c
+	}
+}
+
+// WithQuantileMethod makes the reporter estimate histogram percentiles with
+// the given method instead of the method of its sink.
+func WithQuantileMethod(m sample.QuantileMethod) StdReporterOption {
+	return func(s *stdReporter) {
+		s.quantileMethod = m
+		s.quantileMethodSet = true
+	}
+}
+
+// WithSink makes the reporter log metrics with the given sink instead of
+// NewSlogSink(), which logs them to slog.Default() at info level with an
+// empty message and DefaultAttrNames.  A nil sink is ignored.
+func WithSink(sink *SlogSink) StdReporterOption {
+	return func(s *stdReporter) {
+		if sink != nil {
+			s.sink = sink
+		}
+	}
 }
 
 func (s *stdReporter) RegisterMetrics(metrics []NamedMetric) {
@@ -56,6 +104,10 @@ func (s *stdReporter) UpdateHistogram(name string, v int64) {
 	s.r.Get(name).(histogram.Histogram).Update(v)
 }
 
+func (s *stdReporter) UpdateFloat64Histogram(name string, v float64) {
+	s.r.Get(name).(histogram.Float64Histogram).Update(v)
+}
+
 func (s *stdReporter) IncGauge(name string, v int64) {
 	s.r.Get(name).(guage.Gauge).Inc(v)
 }
@@ -69,47 +121,36 @@ func (s *stdReporter) ReportPeriodically(ctx context.Context, interval time.Dura
 		select {
 		case <-ctx.Done():
 			return
-		case <-time.After(interval):
+		case <-s.clock.After(interval):
+			m := s.sink.quantileMethod
+			if s.quantileMethodSet {
+				m = s.quantileMethod
+			}
 			for _, metricVal := range s.Metrics() {
-				name := metricVal.Name()
-				metric := metricVal.Value()
-				switch instance := metric.(type) {
-				case counter.Counter:
-					slog.Info("", slog.String("name", name), slog.Int64("val", instance.Snapshot()))
-				case guage.Gauge:
-					slog.Info("", slog.String("name", name), slog.Int64("val", instance.SnapShotAndReset()))
-				case guage.GaugeFloat64:
-					slog.Info("", slog.String("name", name), slog.Float64("val", instance.SnapshotAndReset()))
-				case histogram.Histogram:
-					h := instance.Sample().SnapshotAndReset()
-					ps := h.Percentiles([]float64{0.5, 0.95, 0.99, 0.999})
-					slog.Info(
-						"",
-						slog.String("name", name),
-						slog.Int64("count", h.ReqCount()),
-						slog.Int64("sample", h.Count()),
-						slog.Int64("min", h.Min()),
-						slog.Int64("max", h.Max()),
-						slog.Float64("mean", h.Mean()),
-						slog.Float64("stddev", h.StdDev()),
-						slog.Float64("50%", ps[0]),
-						slog.Float64("95%", ps[1]),
-						slog.Float64("99%", ps[2]),
-						slog.Float64("99.9%", ps[3]),
-					)
-				}
+				s.sink.emit(ctx, metricVal.Name(), metricVal.Value(), m)
 			}
 		}
 	}
 }
 
-func NewStdReporter(metrics []NamedMetric) Reporter {
+func NewStdReporter(metrics []NamedMetric, opts ...StdReporterOption) Reporter {
 	res := &stdReporter{
 		r:       NewRegistry(),
 		metrics: metrics,
+		clock:   clock.System(),
+		sink:    NewSlogSink(),
+	}
+	for _, opt := range opts {
+		opt(res)
 	}
 	for _, metric := range metrics {
 		res.r.Register(metric.name, metric.m)
 	}
 	return res
 }
+
+// NewStdReporterWithClock constructs a reporter whose reporting intervals are
+// measured by the given clock.
+func NewStdReporterWithClock(metrics []NamedMetric, c clock.Clock) Reporter {
+	return NewStdReporter(metrics, WithClock(c))
+}
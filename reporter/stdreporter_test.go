@@ -2,8 +2,10 @@ package reporter
 
 import (
 	"context"
+	"github.com/someview/go-metrics/clock/clocktest"
 	"github.com/someview/go-metrics/histogram"
 	"github.com/someview/go-metrics/sample"
+	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
 )
@@ -11,10 +13,35 @@ import (
 func TestStdReporter_ReportPeriodically(t *testing.T) {
 	metrics := []NamedMetric{
 		NewHistogramMetric("disk", histogram.NewHistogram(sample.NewSlidingWindowSample(1))),
+		NewFloat64HistogramMetric("ratio", histogram.NewFloat64Histogram(sample.NewSlidingWindowFloat64Sample(1))),
 	}
 	r := NewStdReporter(metrics)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 	r.UpdateHistogram("disk", 1)
+	r.(Float64HistogramUpdater).UpdateFloat64Histogram("ratio", 0.5)
 	r.ReportPeriodically(ctx, 1)
 }
+
+func TestStdReporter_ReportPeriodicallyWithClock(t *testing.T) {
+	h := histogram.NewHistogram(sample.NewSlidingWindowSample(10))
+	c := clocktest.NewClock(time.Now())
+	r := NewStdReporterWithClock([]NamedMetric{NewHistogramMetric("disk", h)}, c)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.ReportPeriodically(ctx, time.Minute)
+		close(done)
+	}()
+	r.UpdateHistogram("disk", 1)
+
+	c.BlockUntil(1)
+	c.Add(59 * time.Second)
+	assert.Equal(t, int64(1), h.Sample().Snapshot().ReqCount())
+	c.Add(time.Second)
+	// The report resets the histogram before the reporter waits again.
+	c.BlockUntil(1)
+	assert.Equal(t, int64(0), h.Sample().Snapshot().ReqCount())
+	cancel()
+	<-done
+}
@@ -0,0 +1,211 @@
+// Package runtimemetrics exposes the Go runtime/metrics package as gauges
+// and histograms of a reporter.Registry.
+package runtimemetrics
+
+import (
+	"context"
+	"math"
+	"runtime/metrics"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/someview/go-metrics/clock"
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/internal/instrument"
+	"github.com/someview/go-metrics/reporter"
+)
+
+// DefaultAllowlist is the set of runtime/metrics names collected unless
+// WithAllowlist is given: heap size, GC cycles and pauses, goroutines,
+// scheduler latency and mutex wait time.
+var DefaultAllowlist = []string{
+	"/memory/classes/heap/objects:bytes",
+	"/gc/heap/goal:bytes",
+	"/gc/cycles/total:gc-cycles",
+	"/sched/pauses/total/gc:seconds",
+	"/sched/goroutines:goroutines",
+	"/sched/latencies:seconds",
+	"/sync/mutex/wait/total:seconds",
+}
+
+// DefaultMaxAge is how long values read from the runtime are reused before a
+// gauge read triggers a fresh read.  It lets a reporter walk every gauge of
+// the collector with a single runtime/metrics.Read.
+const DefaultMaxAge = time.Second
+
+// Collector reads runtime/metrics and exposes each allowlisted metric in a
+// registry, named after it with WithPrefix prepended (see Name):
+//
+//   - uint64 metrics as guage.Gauge
+//   - float64 metrics as guage.GaugeFloat64
+//   - distributions such as GC pauses as histogram.Float64Histogram, whose
+//     snapshots cover the values recorded by the runtime since the last
+//     SnapshotAndReset.
+//
+// By default values are read lazily when a gauge or histogram is read and the
+// last read is older than DefaultMaxAge.  Run refreshes them on a schedule
+// instead.
+type Collector struct {
+	mutex     sync.Mutex
+	clock     clock.Clock
+	prefix    string
+	allowlist []string
+	maxAge    time.Duration
+	samples   []metrics.Sample
+	lastRead  time.Time
+}
+
+// Option configures a Collector.
+type Option func(*Collector)
+
+// WithPrefix sets the prefix of registered metric names, "runtime" by default.
+func WithPrefix(prefix string) Option {
+	return func(c *Collector) {
+		c.prefix = prefix
+	}
+}
+
+// WithAllowlist sets the runtime/metrics names to collect.  Names the running
+// Go version does not support are skipped.
+func WithAllowlist(names ...string) Option {
+	return func(c *Collector) {
+		c.allowlist = names
+	}
+}
+
+// WithMaxAge sets how long values are reused before a read triggers a fresh
+// one.  A negative age disables lazy reads, leaving refreshes to Collect and
+// Run.
+func WithMaxAge(d time.Duration) Option {
+	return func(c *Collector) {
+		c.maxAge = d
+	}
+}
+
+// WithClock sets the clock used for max age and Run's interval.
+func WithClock(clk clock.Clock) Option {
+	return func(c *Collector) {
+		c.clock = clk
+	}
+}
+
+// NewCollector constructs a collector.  Call Register to expose its metrics.
+func NewCollector(opts ...Option) *Collector {
+	c := &Collector{
+		clock:     clock.System(),
+		prefix:    "runtime",
+		allowlist: DefaultAllowlist,
+		maxAge:    DefaultMaxAge,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	kinds := make(map[string]metrics.ValueKind)
+	for _, d := range metrics.All() {
+		kinds[d.Name] = d.Kind
+	}
+	for _, name := range c.allowlist {
+		if kind, ok := kinds[name]; ok && kind != metrics.KindBad {
+			c.samples = append(c.samples, metrics.Sample{Name: name})
+		}
+	}
+	return c
+}
+
+// Name returns the registry name of a runtime/metrics metric: its path and
+// unit joined with dots after the prefix, e.g. "runtime.gc.heap.goal.bytes".
+func Name(prefix, name string) string {
+	name = strings.NewReplacer("/", ".", ":", ".").Replace(strings.TrimPrefix(name, "/"))
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// Register registers a gauge or histogram for every collected metric.  If
+// one cannot be registered, none is.
+func (c *Collector) Register(r reporter.Registry) error {
+	c.Collect()
+	all := make(map[string]interface{}, len(c.samples))
+	for i, s := range c.samples {
+		var metric interface{}
+		switch s.Value.Kind() {
+		case metrics.KindUint64:
+			metric = guage.NewFunctionalGauge(func() int64 {
+				return int64(c.value(i).Uint64())
+			})
+		case metrics.KindFloat64:
+			metric = guage.NewFunctionalGaugeFloat64(func() float64 {
+				return c.value(i).Float64()
+			})
+		case metrics.KindFloat64Histogram:
+			metric = newRuntimeHistogram(c, i)
+		default:
+			continue
+		}
+		all[Name(c.prefix, s.Name)] = metric
+	}
+	return instrument.Register(r, all)
+}
+
+// Collect reads every collected metric from the runtime.
+func (c *Collector) Collect() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.collect()
+}
+
+func (c *Collector) collect() {
+	metrics.Read(c.samples)
+	c.lastRead = c.clock.Now()
+}
+
+// Run calls Collect every interval until ctx is done.
+func (c *Collector) Run(ctx context.Context, interval time.Duration) {
+	ticker := c.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			c.Collect()
+		}
+	}
+}
+
+// value returns the i-th sample's value, reading it afresh if it is stale.
+func (c *Collector) value(i int) metrics.Value {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.maxAge >= 0 && c.clock.Now().Sub(c.lastRead) > c.maxAge {
+		c.collect()
+	}
+	return c.samples[i].Value
+}
+
+// histogram returns a copy of the i-th sample's bucket counts and boundaries,
+// reading them afresh if they are stale.  They are copied because the
+// runtime reuses their buffers on the next read.
+func (c *Collector) histogram(i int) ([]uint64, []float64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.maxAge >= 0 && c.clock.Now().Sub(c.lastRead) > c.maxAge {
+		c.collect()
+	}
+	h := c.samples[i].Value.Float64Histogram()
+	return append([]uint64(nil), h.Counts...), append([]float64(nil), h.Buckets...)
+}
+
+// bucketValue returns the value representing a bucket: its midpoint, or its
+// finite bound if the other is infinite.
+func bucketValue(lower, upper float64) float64 {
+	switch {
+	case math.IsInf(lower, -1):
+		return upper
+	case math.IsInf(upper, 1):
+		return lower
+	}
+	return lower + (upper-lower)/2
+}
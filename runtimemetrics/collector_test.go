@@ -0,0 +1,110 @@
+package runtimemetrics
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/someview/go-metrics/clock/clocktest"
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "runtime.gc.heap.goal.bytes", Name("runtime", "/gc/heap/goal:bytes"))
+	assert.Equal(t, "sched.goroutines.goroutines", Name("", "/sched/goroutines:goroutines"))
+}
+
+func TestCollectorRegister(t *testing.T) {
+	r := reporter.NewRegistry()
+	c := NewCollector()
+	require.NoError(t, c.Register(r))
+
+	goroutines, ok := r.Get("runtime.sched.goroutines.goroutines").(guage.Gauge)
+	require.True(t, ok)
+	assert.Positive(t, goroutines.Snapshot())
+	heap, ok := r.Get("runtime.memory.classes.heap.objects.bytes").(guage.Gauge)
+	require.True(t, ok)
+	assert.Positive(t, heap.Snapshot())
+	_, ok = r.Get("runtime.sync.mutex.wait.total.seconds").(guage.GaugeFloat64)
+	assert.True(t, ok)
+
+	pauses, ok := r.Get("runtime.sched.pauses.total.gc.seconds").(histogram.Float64Histogram)
+	require.True(t, ok)
+	pauses.Clear()
+	runtime.GC()
+	runtime.GC()
+	c.Collect()
+	s := pauses.Sample().SnapshotAndReset()
+	assert.GreaterOrEqual(t, s.ReqCount(), int64(2))
+	assert.Positive(t, s.Count())
+	assert.Positive(t, s.Max())
+	assert.LessOrEqual(t, s.Min(), s.Max())
+	c.Collect()
+	assert.Zero(t, pauses.Sample().Snapshot().ReqCount())
+	pauses.Update(1)
+	assert.Zero(t, pauses.Sample().Snapshot().ReqCount())
+}
+
+func TestCollectorRegisterConflict(t *testing.T) {
+	r := reporter.NewRegistry()
+	require.NoError(t, r.Register("runtime.gc.heap.goal.bytes", counter.NewCounter()))
+	assert.Error(t, NewCollector().Register(r))
+	assert.Nil(t, r.Get("runtime.sched.goroutines.goroutines"))
+	assert.Nil(t, r.Get("runtime.sched.pauses.total.gc.seconds"))
+}
+
+func TestCollectorAllowlist(t *testing.T) {
+	r := reporter.NewRegistry()
+	c := NewCollector(
+		WithPrefix("go"),
+		WithAllowlist("/gc/cycles/total:gc-cycles", "/no/such/metric:bytes"),
+	)
+	require.NoError(t, c.Register(r))
+	var names []string
+	r.Each(func(name string, _ interface{}) { names = append(names, name) })
+	assert.Equal(t, []string{"go.gc.cycles.total.gc-cycles"}, names)
+}
+
+func TestCollectorRun(t *testing.T) {
+	clk := clocktest.NewClock(time.Unix(0, 0))
+	r := reporter.NewRegistry()
+	c := NewCollector(WithClock(clk), WithMaxAge(-1), WithAllowlist("/gc/cycles/total:gc-cycles"))
+	require.NoError(t, c.Register(r))
+	cycles := r.Get("runtime.gc.cycles.total.gc-cycles").(guage.Gauge)
+
+	before := cycles.Snapshot()
+	runtime.GC()
+	assert.Equal(t, before, cycles.Snapshot(), "lazy reads are disabled")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx, time.Second)
+		close(done)
+	}()
+	clk.BlockUntil(1)
+	clk.Add(time.Second)
+	assert.Eventually(t, func() bool { return cycles.Snapshot() > before }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+}
+
+func TestCollectorMaxAge(t *testing.T) {
+	clk := clocktest.NewClock(time.Unix(0, 0))
+	r := reporter.NewRegistry()
+	c := NewCollector(WithClock(clk), WithAllowlist("/gc/cycles/total:gc-cycles"))
+	require.NoError(t, c.Register(r))
+	cycles := r.Get("runtime.gc.cycles.total.gc-cycles").(guage.Gauge)
+
+	before := cycles.Snapshot()
+	runtime.GC()
+	assert.Equal(t, before, cycles.Snapshot())
+	clk.Add(DefaultMaxAge + time.Millisecond)
+	assert.Greater(t, cycles.Snapshot(), before)
+}
@@ -0,0 +1,93 @@
+package runtimemetrics
+
+import (
+	"math"
+	"sync"
+
+	"github.com/someview/go-metrics/sample"
+)
+
+// histogramSize bounds the number of values in a runtime histogram snapshot.
+const histogramSize = 1028
+
+// runtimeHistogram exposes a cumulative runtime/metrics distribution as both
+// a histogram.Float64Histogram and its sample.  Snapshots cover the counts
+// recorded since the last SnapshotAndReset or Clear; each bucket contributes
+// its representative value (see bucketValue) in proportion to its count, so
+// statistics are accurate to the bucket resolution.
+type runtimeHistogram struct {
+	mutex     sync.Mutex
+	collector *Collector
+	index     int
+	base      []uint64
+}
+
+func newRuntimeHistogram(c *Collector, i int) *runtimeHistogram {
+	h := &runtimeHistogram{collector: c, index: i}
+	h.base, _ = c.histogram(i)
+	return h
+}
+
+// Clear starts the next snapshot from the runtime's current counts.
+func (h *runtimeHistogram) Clear() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.base, _ = h.collector.histogram(h.index)
+}
+
+// Sample returns the histogram itself, which implements sample.Float64Sample.
+func (h *runtimeHistogram) Sample() sample.Float64Sample { return h }
+
+// Update does nothing: the values of a runtime histogram are recorded by the
+// runtime.
+func (h *runtimeHistogram) Update(float64) {}
+
+// Snapshot returns the values recorded since the last reset.
+func (h *runtimeHistogram) Snapshot() sample.Float64SampleSnapshot {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	counts, buckets := h.collector.histogram(h.index)
+	return h.snapshot(counts, buckets)
+}
+
+// SnapshotAndReset returns the values recorded since the last reset and
+// resets the histogram.
+func (h *runtimeHistogram) SnapshotAndReset() sample.Float64SampleSnapshot {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	counts, buckets := h.collector.histogram(h.index)
+	res := h.snapshot(counts, buckets)
+	h.base = counts
+	return res
+}
+
+func (h *runtimeHistogram) snapshot(counts []uint64, buckets []float64) sample.Float64SampleSnapshot {
+	deltas := make([]uint64, len(counts))
+	var total uint64
+	for i, n := range counts {
+		if i < len(h.base) && h.base[i] <= n {
+			n -= h.base[i]
+		}
+		deltas[i] = n
+		total += n
+	}
+	stats := sample.Float64Stats{Count: int64(total), Min: math.Inf(1), Max: math.Inf(-1)}
+	values := make([]float64, 0, min(total, histogramSize))
+	for i, n := range deltas {
+		if n == 0 {
+			continue
+		}
+		v := bucketValue(buckets[i], buckets[i+1])
+		stats.Sum += v * float64(n)
+		stats.Min = math.Min(stats.Min, v)
+		stats.Max = math.Max(stats.Max, v)
+		k := n
+		if total > histogramSize {
+			k = uint64(math.Round(float64(n) * histogramSize / float64(total)))
+		}
+		for j := uint64(0); j < k; j++ {
+			values = append(values, v)
+		}
+	}
+	return sample.NewFloat64SampleSnapshot(int64(len(values)), values, stats)
+}
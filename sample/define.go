@@ -17,8 +17,61 @@ type SampleSnapshot interface {
 	Min() int64
 	Percentile(float64) float64
 	Percentiles([]float64) []float64
+	PercentilesMethod([]float64, QuantileMethod) []float64
 	Size() int
 	Sum() int64
 	StdDev() float64
 	Variance() float64
+
+	// The Exact accessors cover every value passed to Update since the last
+	// reset, not only the values retained by the sample.
+	ExactMax() int64
+	ExactMean() float64
+	ExactMin() int64
+	ExactSum() int64
+
+	// Merge returns a snapshot combining this one with other, leaving both
+	// untouched.  See MergeSnapshots for the semantics of each sample type.
+	Merge(other SampleSnapshot) SampleSnapshot
+
+	// Summary returns all of the above along with the given percentiles.
+	Summary(ps []float64) Summary
+}
+
+// Float64Samples maintain a statistically-significant selection of float64
+// values from a stream.
+type Float64Sample interface {
+	Clear()
+	Snapshot() Float64SampleSnapshot
+	SnapshotAndReset() Float64SampleSnapshot
+	Update(float64)
+}
+
+type Float64SampleSnapshot interface {
+	ReqCount() int64 // 请求次数
+	Count() int64    // 采样次数
+	Max() float64
+	Mean() float64
+	Min() float64
+	Percentile(float64) float64
+	Percentiles([]float64) []float64
+	PercentilesMethod([]float64, QuantileMethod) []float64
+	Size() int
+	Sum() float64
+	StdDev() float64
+	Variance() float64
+
+	// The Exact accessors cover every value passed to Update since the last
+	// reset, not only the values retained by the sample.
+	ExactMax() float64
+	ExactMean() float64
+	ExactMin() float64
+	ExactSum() float64
+
+	// Merge returns a snapshot combining this one with other, leaving both
+	// untouched.  See MergeFloat64Snapshots for its semantics.
+	Merge(other Float64SampleSnapshot) Float64SampleSnapshot
+
+	// Summary returns all of the above along with the given percentiles.
+	Summary(ps []float64) Float64Summary
 }
@@ -5,6 +5,8 @@ import (
 	"math/rand"
 	"sync"
 	"time"
+
+	"github.com/someview/go-metrics/clock"
 )
 
 const rescaleThreshold = time.Hour
@@ -16,24 +18,52 @@ const rescaleThreshold = time.Hour
 // <http://dimacs.rutgers.edu/~graham/pubs/papers/fwddecay.pdf>
 type ExpDecaySample struct {
 	alpha         float64
+	clock         clock.Clock
 	count         int64
 	mutex         sync.Mutex
+	rand          *rand.Rand
 	reservoirSize int
 	t0, t1        time.Time
 	values        *expDecaySampleHeap
-	reqCount      int64
+	stats         Stats
 }
 
 // NewExpDecaySample constructs a new exponentially-decaying sample with the
 // given reservoir size and alpha.
 func NewExpDecaySample(reservoirSize int, alpha float64) Sample {
+	return NewExpDecaySampleWithClock(reservoirSize, alpha, clock.System())
+}
+
+// NewDefaultSample constructs the sample the instrumentation packages give
+// their histograms unless configured otherwise: an exponentially-decaying
+// sample of 1028 values with an alpha of 0.015, which is heavily biased
+// towards the last 5 minutes.
+func NewDefaultSample() Sample {
+	return NewExpDecaySample(1028, 0.015)
+}
+
+// NewExpDecaySampleWithClock constructs a new exponentially-decaying sample
+// that timestamps updates and rescales using the given clock.
+func NewExpDecaySampleWithClock(reservoirSize int, alpha float64, c clock.Clock) Sample {
+	return NewExpDecaySampleWithSource(reservoirSize, alpha, c, nil)
+}
+
+// NewExpDecaySampleWithSource constructs a new exponentially-decaying sample
+// that draws the priorities of updates from src, so that which values it
+// retains is reproducible.  A nil src uses the shared source of math/rand.
+func NewExpDecaySampleWithSource(reservoirSize int, alpha float64, c clock.Clock, src rand.Source) Sample {
 	s := &ExpDecaySample{
 		alpha:         alpha,
+		clock:         c,
 		reservoirSize: reservoirSize,
-		t0:            time.Now(),
+		t0:            c.Now(),
 		values:        newExpDecaySampleHeap(reservoirSize),
 	}
+	if src != nil {
+		s.rand = rand.New(src)
+	}
 	s.t1 = s.t0.Add(rescaleThreshold)
+	s.stats.reset()
 	return s
 }
 
@@ -46,8 +76,8 @@ func (s *ExpDecaySample) Clear() {
 
 func (s *ExpDecaySample) reset() {
 	s.count = 0
-	s.reqCount = 0
-	s.t0 = time.Now()
+	s.stats.reset()
+	s.t0 = s.clock.Now()
 	s.t1 = s.t0.Add(rescaleThreshold)
 	s.values.Clear()
 }
@@ -62,9 +92,9 @@ func (s *ExpDecaySample) Snapshot() SampleSnapshot {
 		values[i] = v.v
 	}
 	return &sampleSnapshot{
-		count:    s.count,
-		values:   values,
-		reqCount: s.reqCount,
+		count:  s.count,
+		values: values,
+		stats:  s.stats,
 	}
 }
 
@@ -76,12 +106,13 @@ func (s *ExpDecaySample) SnapshotAndReset() SampleSnapshot {
 	for i, v := range vals {
 		values[i] = v.v
 	}
-	s.reset()
-	return &sampleSnapshot{
-		count:    s.count,
-		values:   values,
-		reqCount: s.reqCount,
+	res := &sampleSnapshot{
+		count:  s.count,
+		values: values,
+		stats:  s.stats,
 	}
+	s.reset()
+	return res
 }
 
 // Values returns a copy of the values in the sample.
@@ -96,20 +127,19 @@ func (s *ExpDecaySample) Values() []int64 {
 
 // Update samples a new value.
 func (s *ExpDecaySample) Update(v int64) {
-	s.update(time.Now(), v)
+	s.update(s.clock.Now(), v)
 }
 
-// update samples a new value at a particular timestamp.  This is a method all
-// its own to facilitate testing.
+// update samples a new value at a particular timestamp.
 func (s *ExpDecaySample) update(t time.Time, v int64) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	s.reqCount++
+	s.stats.update(v)
 	if s.values.Size() == s.reservoirSize {
 		s.values.Pop()
 	}
 	s.values.Push(expDecaySample{
-		k: math.Exp(t.Sub(s.t0).Seconds()*s.alpha) / rand.Float64(),
+		k: math.Exp(t.Sub(s.t0).Seconds()*s.alpha) / s.float64(),
 		v: v,
 	})
 	if t.After(s.t1) {
@@ -126,6 +156,15 @@ func (s *ExpDecaySample) update(t time.Time, v int64) {
 	s.count = int64(s.values.Size())
 }
 
+// float64 returns a pseudo-random number in [0.0,1.0) from the sample's
+// source.
+func (s *ExpDecaySample) float64() float64 {
+	if s.rand != nil {
+		return s.rand.Float64()
+	}
+	return rand.Float64()
+}
+
 // expDecaySample represents an individual sample in a heap.
 type expDecaySample struct {
 	k float64
@@ -77,10 +77,16 @@ func SamplePercentile(values int64Slice, p float64) float64 {
 // SamplePercentiles returns a slice of arbitrary percentiles of the slice of
 // int64.
 func SamplePercentiles(values int64Slice, ps []float64) []float64 {
+	sort.Sort(values)
+	return sortedPercentiles(values, ps)
+}
+
+// sortedPercentiles returns percentiles of values sorted in ascending order,
+// interpolating linearly between the values around position p*(n+1).
+func sortedPercentiles[T int64 | float64](values []T, ps []float64) []float64 {
 	scores := make([]float64, len(ps))
 	size := len(values)
 	if size > 0 {
-		sort.Sort(values)
 		for i, p := range ps {
 			pos := p * float64(size+1)
 			if pos < 1.0 {
@@ -0,0 +1,83 @@
+package sample
+
+import (
+	"math"
+	"sort"
+)
+
+// SampleStdDevFloat64 returns the standard deviation of the slice of float64.
+func SampleStdDevFloat64(values []float64) float64 {
+	return math.Sqrt(SampleVarianceFloat64(values))
+}
+
+// SampleSumFloat64 returns the sum of the slice of float64.
+func SampleSumFloat64(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum
+}
+
+// SampleVarianceFloat64 returns the variance of the slice of float64.
+func SampleVarianceFloat64(values []float64) float64 {
+	if 0 == len(values) {
+		return 0.0
+	}
+	m := SampleMeanFloat64(values)
+	var sum float64
+	for _, v := range values {
+		d := v - m
+		sum += d * d
+	}
+	return sum / float64(len(values))
+}
+
+// SampleMaxFloat64 returns the maximum value of the slice of float64.
+func SampleMaxFloat64(values []float64) float64 {
+	if 0 == len(values) {
+		return 0.0
+	}
+	max := math.Inf(-1)
+	for _, v := range values {
+		if max < v {
+			max = v
+		}
+	}
+	return max
+}
+
+// SampleMeanFloat64 returns the mean value of the slice of float64.
+func SampleMeanFloat64(values []float64) float64 {
+	if 0 == len(values) {
+		return 0.0
+	}
+	return SampleSumFloat64(values) / float64(len(values))
+}
+
+// SampleMinFloat64 returns the minimum value of the slice of float64.
+func SampleMinFloat64(values []float64) float64 {
+	if 0 == len(values) {
+		return 0.0
+	}
+	min := math.Inf(1)
+	for _, v := range values {
+		if min > v {
+			min = v
+		}
+	}
+	return min
+}
+
+// SamplePercentileFloat64 returns an arbitrary percentile of the slice of
+// float64.
+func SamplePercentileFloat64(values []float64, p float64) float64 {
+	return SamplePercentilesFloat64(values, []float64{p})[0]
+}
+
+// SamplePercentilesFloat64 returns a slice of arbitrary percentiles of the
+// slice of float64, interpolating the same way as SamplePercentiles.
+func SamplePercentilesFloat64(values []float64, ps []float64) []float64 {
+	sort.Float64s(values)
+	return sortedPercentiles(values, ps)
+}
@@ -0,0 +1,127 @@
+package sample
+
+import (
+	"math"
+	"slices"
+)
+
+// MergeSnapshots combines snapshots taken from several samples, e.g. the same
+// histogram on several replicas, into one.  The statistical semantics depend
+// on the sample type:
+//
+//   - ReqCount and the Exact accessors are always exact.
+//   - Reservoir snapshots (SlidingWindowSample, ExpDecaySample,
+//     ShardedSample) merge approximately.  Each retained value stands for
+//     ReqCount/Count updates; the denser reservoir is thinned so that every
+//     value in the merged reservoir stands for the same number of updates,
+//     keeping percentiles unbiased when the replicas saw different traffic.
+//     The estimates are only as good as the sparsest reservoir.
+//   - t-digest snapshots merge centroids and keep the digest's rank-error
+//     bounds.  Merging a t-digest with a reservoir snapshot yields a t-digest.
+//
+// MergeSnapshots returns an empty snapshot when given none.
+func MergeSnapshots(snapshots ...SampleSnapshot) SampleSnapshot {
+	var res SampleSnapshot = NewSampleSnapshotWithStats(0, nil, emptyStats())
+	for _, s := range snapshots {
+		if s != nil {
+			res = res.Merge(s)
+		}
+	}
+	return res
+}
+
+// MergeFloat64Snapshots combines float64 snapshots taken from several samples
+// into one, with the reservoir semantics described for MergeSnapshots.
+func MergeFloat64Snapshots(snapshots ...Float64SampleSnapshot) Float64SampleSnapshot {
+	var empty Float64Stats
+	empty.reset()
+	var res Float64SampleSnapshot = NewFloat64SampleSnapshot(0, nil, empty)
+	for _, s := range snapshots {
+		if s != nil {
+			res = res.Merge(s)
+		}
+	}
+	return res
+}
+
+// Merge returns a snapshot combining s and other; see MergeSnapshots.
+func (s *sampleSnapshot) Merge(other SampleSnapshot) SampleSnapshot {
+	switch o := other.(type) {
+	case nil:
+		return s
+	case *TDigest:
+		return o.Merge(s)
+	}
+	values := mergeReservoirs(s.values, s.stats.Count, snapshotValues(other), other.ReqCount())
+	stats := s.stats.merge(SnapshotStats(other))
+	return NewSampleSnapshotWithStats(int64(len(values)), values, stats)
+}
+
+// Merge returns a snapshot combining s and other; see MergeFloat64Snapshots.
+func (s *float64SampleSnapshot) Merge(other Float64SampleSnapshot) Float64SampleSnapshot {
+	if other == nil {
+		return s
+	}
+	var otherValues []float64
+	if v, ok := other.(interface{ Values() []float64 }); ok {
+		otherValues = v.Values()
+	}
+	otherStats := Float64Stats{
+		Count: other.ReqCount(),
+		Sum:   other.ExactSum(),
+		Min:   math.Inf(1),
+		Max:   math.Inf(-1),
+	}
+	if otherStats.Count > 0 {
+		otherStats.Min, otherStats.Max = other.ExactMin(), other.ExactMax()
+	}
+	values := mergeReservoirs(s.values, s.stats.Count, otherValues, other.ReqCount())
+	return NewFloat64SampleSnapshot(int64(len(values)), values, s.stats.merge(otherStats))
+}
+
+func emptyStats() Stats {
+	var s Stats
+	s.reset()
+	return s
+}
+
+// snapshotValues returns the retained values of a snapshot, or nil if it does
+// not keep any.
+func snapshotValues(s SampleSnapshot) []int64 {
+	if v, ok := s.(interface{ Values() []int64 }); ok {
+		return v.Values()
+	}
+	return nil
+}
+
+// mergeReservoirs combines two reservoirs drawn from streams of na and nb
+// updates.  The reservoir whose values stand for fewer updates each is
+// thinned so that every value in the result stands for the same number.
+func mergeReservoirs[T int64 | float64](a []T, na int64, b []T, nb int64) []T {
+	if len(a) == 0 || na == 0 {
+		return slices.Clone(b)
+	}
+	if len(b) == 0 || nb == 0 {
+		return slices.Clone(a)
+	}
+	w := math.Max(float64(na)/float64(len(a)), float64(nb)/float64(len(b)))
+	res := make([]T, 0, len(a)+len(b))
+	res = append(res, thin(a, int(math.Round(float64(na)/w)))...)
+	res = append(res, thin(b, int(math.Round(float64(nb)/w)))...)
+	return res
+}
+
+// thin returns n values evenly spaced across the sorted values, so the
+// result keeps the shape of the distribution.
+func thin[T int64 | float64](values []T, n int) []T {
+	sorted := slices.Clone(values)
+	if n >= len(values) {
+		return sorted
+	}
+	slices.Sort(sorted)
+	res := make([]T, n)
+	for i := range res {
+		res[i] = sorted[(2*i+1)*len(sorted)/(2*n)]
+	}
+	return res
+}
@@ -0,0 +1,79 @@
+package sample
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeSnapshots(t *testing.T) {
+	a := NewSlidingWindowSample(100)
+	b := NewSlidingWindowSample(100)
+	for i := int64(1); i <= 1000; i++ {
+		a.Update(i)
+	}
+	for i := int64(1); i <= 100; i++ {
+		b.Update(-i)
+	}
+	merged := MergeSnapshots(a.Snapshot(), b.Snapshot())
+	assert.Equal(t, int64(1100), merged.ReqCount())
+	assert.Equal(t, int64(-100), merged.ExactMin())
+	assert.Equal(t, int64(1000), merged.ExactMax())
+	assert.Equal(t, int64(500500-5050), merged.ExactSum())
+	// a's values stand for 10 updates each and b's for one, so b is thinned
+	// to 10 values to keep the proportions of the combined stream.
+	assert.Equal(t, int64(110), merged.Count())
+	negative := 0
+	for _, v := range merged.(*sampleSnapshot).values {
+		if v < 0 {
+			negative++
+		}
+	}
+	assert.Equal(t, 10, negative)
+
+	empty := MergeSnapshots()
+	assert.Equal(t, int64(0), empty.ReqCount())
+	assert.Equal(t, int64(0), empty.ExactMax())
+	assert.Equal(t, int64(1000), MergeSnapshots(empty, a.Snapshot()).ExactMax())
+}
+
+func TestMergeSnapshotsWithTDigest(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	d := NewTDigestSample(DefaultTDigestCompression)
+	w := NewSlidingWindowSample(10000)
+	values := make([]int64, 0, 20000)
+	for i := 0; i < 10000; i++ {
+		v := r.Int63n(10000)
+		d.Update(v)
+		values = append(values, v)
+		v = r.Int63n(10000) + 10000
+		w.Update(v)
+		values = append(values, v)
+	}
+	merged := MergeSnapshots(w.Snapshot(), d.Snapshot())
+	_, ok := merged.(*TDigest)
+	assert.True(t, ok)
+	assert.Equal(t, int64(20000), merged.ReqCount())
+	assert.Equal(t, SampleSum(values), merged.ExactSum())
+	assert.Equal(t, SampleMin(values), merged.ExactMin())
+	assert.Equal(t, SampleMax(values), merged.ExactMax())
+	assert.InDelta(t, SamplePercentile(values, 0.5), merged.Percentile(0.5), 100)
+	assert.InDelta(t, SamplePercentile(values, 0.99), merged.Percentile(0.99), 50)
+}
+
+func TestMergeFloat64Snapshots(t *testing.T) {
+	a := NewSlidingWindowFloat64Sample(10)
+	b := NewSlidingWindowFloat64Sample(10)
+	for i := 0; i < 10; i++ {
+		a.Update(0.5)
+		b.Update(1.5)
+	}
+	merged := MergeFloat64Snapshots(a.Snapshot(), b.Snapshot())
+	assert.Equal(t, int64(20), merged.ReqCount())
+	assert.Equal(t, int64(20), merged.Count())
+	assert.Equal(t, 1.0, merged.Mean())
+	assert.Equal(t, 0.5, merged.ExactMin())
+	assert.Equal(t, 1.5, merged.ExactMax())
+	assert.Equal(t, 20.0, merged.ExactSum())
+}
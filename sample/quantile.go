@@ -0,0 +1,254 @@
+package sample
+
+import (
+	"math"
+	"slices"
+)
+
+// QuantileMethod selects how percentiles are estimated from a sample's
+// values.  Methods 1 to 9 are the Hyndman & Fan definitions, numbered as in
+// R's quantile(type = ...).
+//
+// <https://doi.org/10.2307/2684934>
+type QuantileMethod int
+
+const (
+	// DefaultQuantile is the method used by SamplePercentiles and by
+	// snapshots' Percentiles, i.e. HyndmanFan6.
+	DefaultQuantile QuantileMethod = iota
+	// HyndmanFan1 is the inverse of the empirical distribution function.
+	HyndmanFan1
+	// HyndmanFan2 is like HyndmanFan1 but averages at discontinuities.
+	HyndmanFan2
+	// HyndmanFan3 is the observation closest to n*p, as used by SAS.
+	HyndmanFan3
+	// HyndmanFan4 interpolates the empirical distribution function linearly.
+	HyndmanFan4
+	// HyndmanFan5 is piecewise linear with knots at the midpoints, as used by
+	// Hazen.
+	HyndmanFan5
+	// HyndmanFan6 interpolates at p*(n+1), like Excel's PERCENTILE.EXC and
+	// Minitab.
+	HyndmanFan6
+	// HyndmanFan7 interpolates at 1+p*(n-1), like R and NumPy's default
+	// "linear" method and Excel's PERCENTILE.INC.
+	HyndmanFan7
+	// HyndmanFan8 is approximately median-unbiased regardless of the
+	// distribution, and recommended by Hyndman & Fan.
+	HyndmanFan8
+	// HyndmanFan9 is approximately unbiased for normally distributed values.
+	HyndmanFan9
+	// HarrellDavis weights every value with a beta distribution centred on
+	// the percentile, which gives smoother estimates for small samples at
+	// the cost of O(n) work per percentile.
+	//
+	// <https://doi.org/10.1093/biomet/69.3.635>
+	HarrellDavis
+)
+
+const (
+	// NearestRank is the smallest value such that at least p of the values
+	// are less than or equal to it.
+	NearestRank = HyndmanFan1
+	// LinearR7 is the linear interpolation used by R, NumPy and Excel by
+	// default.
+	LinearR7 = HyndmanFan7
+)
+
+// SamplePercentilesMethod returns a slice of arbitrary percentiles of the
+// slice of int64, estimated with the given method.  Unlike SamplePercentiles
+// it leaves values untouched.
+func SamplePercentilesMethod(values []int64, ps []float64, m QuantileMethod) []float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	return sortedQuantiles(sorted, ps, m)
+}
+
+// SamplePercentilesFloat64Method is the float64 counterpart of
+// SamplePercentilesMethod.
+func SamplePercentilesFloat64Method(values []float64, ps []float64, m QuantileMethod) []float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	return sortedQuantiles(sorted, ps, m)
+}
+
+// sortedQuantiles returns percentiles of values sorted in ascending order
+// using the given method.
+func sortedQuantiles[T int64 | float64](values []T, ps []float64, m QuantileMethod) []float64 {
+	switch m {
+	case DefaultQuantile, HyndmanFan6:
+		return sortedPercentiles(values, ps)
+	case HarrellDavis:
+		return harrellDavis(values, ps)
+	}
+	scores := make([]float64, len(ps))
+	if len(values) == 0 {
+		return scores
+	}
+	for i, p := range ps {
+		scores[i] = hyndmanFan(values, math.Max(0, math.Min(1, p)), m)
+	}
+	return scores
+}
+
+// hyndmanFan computes one percentile following R's quantile.default: the
+// result lies between the j-th and (j+1)-th values (1-based, clamped to the
+// ends) at fraction h.
+func hyndmanFan[T int64 | float64](values []T, p float64, m QuantileMethod) float64 {
+	const fuzz = 4 * 2.220446049250313e-16
+	n := float64(len(values))
+	var j, h float64
+	switch m {
+	case HyndmanFan1, HyndmanFan2, HyndmanFan3:
+		np := n * p
+		if m == HyndmanFan3 {
+			np -= 0.5
+		}
+		j = math.Floor(np + fuzz)
+		switch {
+		case m == HyndmanFan1 && np > j:
+			h = 1
+		case m == HyndmanFan2 && np > j:
+			h = 1
+		case m == HyndmanFan2:
+			h = 0.5
+		case m == HyndmanFan3 && (np != j || math.Mod(j, 2) == 1):
+			h = 1
+		}
+	default:
+		var a, b float64
+		switch m {
+		case HyndmanFan4:
+			a, b = 0, 1
+		case HyndmanFan5:
+			a, b = 0.5, 0.5
+		case HyndmanFan7:
+			a, b = 1, 1
+		case HyndmanFan8:
+			a, b = 1.0/3, 1.0/3
+		case HyndmanFan9:
+			a, b = 3.0/8, 3.0/8
+		}
+		pos := a + p*(n+1-a-b)
+		j = math.Floor(pos + fuzz)
+		if h = pos - j; math.Abs(h) < fuzz {
+			h = 0
+		}
+	}
+	lower, upper := orderStatistic(values, int(j)), orderStatistic(values, int(j)+1)
+	if h == 0 {
+		return lower
+	}
+	if h == 1 {
+		return upper
+	}
+	return lower + h*(upper-lower)
+}
+
+// orderStatistic returns the i-th smallest value, 1-based, clamped to the
+// smallest and largest values.
+func orderStatistic[T int64 | float64](values []T, i int) float64 {
+	if i < 1 {
+		return float64(values[0])
+	}
+	if i > len(values) {
+		return float64(values[len(values)-1])
+	}
+	return float64(values[i-1])
+}
+
+// harrellDavis computes the Harrell-Davis estimate of each percentile: the
+// weighted sum of all values, the i-th weighted by the probability that a
+// Beta(p(n+1), (1-p)(n+1)) variable falls in ((i-1)/n, i/n].
+func harrellDavis[T int64 | float64](values []T, ps []float64) []float64 {
+	scores := make([]float64, len(ps))
+	n := len(values)
+	if n == 0 {
+		return scores
+	}
+	for i, p := range ps {
+		if p <= 0 {
+			scores[i] = float64(values[0])
+			continue
+		}
+		if p >= 1 {
+			scores[i] = float64(values[n-1])
+			continue
+		}
+		a, b := p*float64(n+1), (1-p)*float64(n+1)
+		var sum, prev float64
+		for k, v := range values {
+			cur := regularizedIncompleteBeta(a, b, float64(k+1)/float64(n))
+			sum += (cur - prev) * float64(v)
+			prev = cur
+		}
+		scores[i] = sum
+	}
+	return scores
+}
+
+// regularizedIncompleteBeta returns I_x(a, b), the cumulative distribution
+// function of the beta distribution, evaluated with the continued fraction
+// from Numerical Recipes.
+func regularizedIncompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log1p(-x))
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 300
+		epsilon       = 1e-15
+		tiny          = 1e-300
+	)
+	qab, qap, qam := a+b, a+1, a-1
+	c, d := 1.0, 1-qab*x/qap
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		m2 := 2 * fm
+		aa := fm * (b - fm) * x / ((qam + m2) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		aa = -(a + fm) * (qab + fm) * x / ((a + m2) * (qap + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,53 @@
+package sample
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The reference values were computed with R's quantile(x, p, type = 1:9)
+// definitions and, for Harrell-Davis, by numerically integrating the beta
+// density as in Harrell & Davis (1982).
+func TestSamplePercentilesMethod(t *testing.T) {
+	values := []int64{144, 2, 89, 3, 55, 3, 34, 5, 21, 8, 13}
+	ps := []float64{0, 0.1, 0.25, 0.5, 0.9, 0.99, 1}
+	expected := map[QuantileMethod][]float64{
+		HyndmanFan1:  {2, 3, 3, 13, 89, 144, 144},
+		HyndmanFan2:  {2, 3, 3, 13, 89, 144, 144},
+		HyndmanFan3:  {2, 2, 3, 13, 89, 144, 144},
+		HyndmanFan4:  {2, 2.1, 3, 10.5, 85.6, 137.95, 144},
+		HyndmanFan5:  {2, 2.6, 3.5, 13, 111, 144, 144},
+		HyndmanFan6:  {2, 2.2, 3, 13, 133, 144, 144},
+		HyndmanFan7:  {2, 3, 4, 13, 89, 138.5, 144},
+		HyndmanFan8:  {2, 2.4666666667, 3.3333333333, 13, 118.3333333333, 144, 144},
+		HyndmanFan9:  {2, 2.5, 3.375, 13, 116.5, 144, 144},
+		HarrellDavis: {2, 2.576822, 4.705192, 17.091492, 113.588318, 142.413709, 144},
+	}
+	for m, want := range expected {
+		got := SamplePercentilesMethod(values, ps, m)
+		assert.InDeltaSlice(t, want, got, 1e-6, "method %d", m)
+		floats := make([]float64, len(values))
+		for i, v := range values {
+			floats[i] = float64(v)
+		}
+		assert.InDeltaSlice(t, want, SamplePercentilesFloat64Method(floats, ps, m), 1e-6, "method %d", m)
+	}
+	assert.Equal(t, int64(144), values[0], "values must be left unsorted")
+	assert.Equal(t, SamplePercentilesMethod(values, ps, HyndmanFan6), SamplePercentilesMethod(values, ps, DefaultQuantile))
+	assert.Equal(t, []float64{0, 0}, SamplePercentilesMethod(nil, []float64{0.5, 0.99}, HarrellDavis))
+}
+
+func TestSnapshotPercentilesMethod(t *testing.T) {
+	s := NewSlidingWindowSample(10)
+	for i := int64(1); i <= 10; i++ {
+		s.Update(i)
+	}
+	snapshot := s.Snapshot()
+	assert.Equal(t, []float64{2.75, 3.25, 3}, []float64{
+		snapshot.Percentile(0.25),
+		snapshot.PercentilesMethod([]float64{0.25}, LinearR7)[0],
+		snapshot.PercentilesMethod([]float64{0.25}, NearestRank)[0],
+	})
+	assert.InDelta(t, 5.5, snapshot.PercentilesMethod([]float64{0.5}, HarrellDavis)[0], 1e-9)
+}
@@ -1,6 +1,9 @@
 package sample
 
 import (
+	"github.com/someview/go-metrics/clock/clocktest"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
 	"runtime"
 	"testing"
 	"time"
@@ -71,9 +74,12 @@ func BenchmarkExpDecaySample1028(b *testing.B) {
 // effectively freezing the set of samples until a rescale step happens.
 
 func TestExpDecaySampleRescale(t *testing.T) {
-	s := NewExpDecaySample(2, 0.001).(*ExpDecaySample)
-	s.update(time.Now(), 1)
-	s.update(time.Now().Add(time.Hour+time.Microsecond), 1)
+	c := clocktest.NewClock(time.Now())
+	s := NewExpDecaySampleWithClock(2, 0.001, c).(*ExpDecaySample)
+	s.Update(1)
+	c.Add(time.Hour + time.Microsecond)
+	s.Update(1)
+	assert.Equal(t, c.Now(), s.t0)
 	for _, v := range s.values.Values() {
 		if v.k == 0.0 {
 			t.Fatal("v.k == 0.0")
@@ -81,6 +87,36 @@ func TestExpDecaySampleRescale(t *testing.T) {
 	}
 }
 
+func TestExpDecaySampleExactStats(t *testing.T) {
+	s := NewExpDecaySample(10, 0.015)
+	for i := int64(1); i <= 1000; i++ {
+		s.Update(i)
+	}
+	snapshot := s.SnapshotAndReset()
+	assert.Equal(t, int64(1000), snapshot.ReqCount())
+	assert.Equal(t, int64(10), snapshot.Count())
+	assert.Equal(t, int64(1), snapshot.ExactMin())
+	assert.Equal(t, int64(1000), snapshot.ExactMax())
+	assert.Equal(t, int64(500500), snapshot.ExactSum())
+	assert.Equal(t, 500.5, snapshot.ExactMean())
+	assert.Equal(t, int64(0), s.Snapshot().ReqCount())
+}
+
+// The decay favours recent values: after a long quiet period the reservoir
+// is taken over by new values even though it was full.
+func TestExpDecaySampleDecay(t *testing.T) {
+	c := clocktest.NewClock(time.Now())
+	s := NewExpDecaySampleWithSource(100, 0.015, c, rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		s.Update(1)
+	}
+	c.Add(10 * time.Minute)
+	for i := 0; i < 100; i++ {
+		s.Update(2)
+	}
+	assert.Equal(t, 2.0, s.Snapshot().Percentile(0.01))
+}
+
 func benchmarkSample(b *testing.B, s Sample) {
 	var memStats runtime.MemStats
 	runtime.ReadMemStats(&memStats)
@@ -0,0 +1,126 @@
+package sample
+
+import (
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/someview/go-metrics/internal/cpu"
+)
+
+// ShardedSample is a Sample for high-contention Update paths.  Updates are
+// spread over padded shards, each holding a lock-free ring buffer of the
+// most recent values and exact statistics, and the shards are merged when a
+// snapshot is taken.
+//
+// Under concurrent updates a snapshot is not an atomic cut: a value being
+// written while the snapshot is taken may be missed or counted in the next
+// one, and its count and sum may land in different snapshots.
+type ShardedSample struct {
+	shards []sampleShard
+	mask   uint64
+	size   uint64
+}
+
+type sampleShard struct {
+	index  atomic.Uint64
+	count  atomic.Int64
+	sum    atomic.Int64
+	min    atomic.Int64
+	max    atomic.Int64
+	values []atomic.Int64
+	_      [cpu.CacheLinePadSize]byte
+}
+
+// NewShardedSample constructs a sample with the given number of shards, each
+// keeping the last size values, so snapshots hold up to shards*size values.
+// The shard count is rounded up to a power of two; if it is not positive,
+// GOMAXPROCS is used.
+func NewShardedSample(shards int, size uint64) Sample {
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+	n := 1
+	for n < shards {
+		n <<= 1
+	}
+	s := &ShardedSample{
+		shards: make([]sampleShard, n),
+		mask:   uint64(n - 1),
+		size:   size,
+	}
+	for i := range s.shards {
+		s.shards[i].values = make([]atomic.Int64, size)
+		s.shards[i].reset()
+	}
+	return s
+}
+
+func (sh *sampleShard) reset() {
+	sh.index.Store(0)
+	sh.count.Store(0)
+	sh.sum.Store(0)
+	sh.min.Store(math.MaxInt64)
+	sh.max.Store(math.MinInt64)
+}
+
+// Clear clears all samples.
+func (s *ShardedSample) Clear() {
+	for i := range s.shards {
+		s.shards[i].reset()
+	}
+}
+
+// Snapshot returns a read-only copy of the sample.
+func (s *ShardedSample) Snapshot() SampleSnapshot {
+	return s.snapshot(false)
+}
+
+// SnapshotAndReset returns a read-only copy of the sample and resets it.
+func (s *ShardedSample) SnapshotAndReset() SampleSnapshot {
+	return s.snapshot(true)
+}
+
+func (s *ShardedSample) snapshot(reset bool) SampleSnapshot {
+	var stats Stats
+	stats.reset()
+	values := make([]int64, 0, uint64(len(s.shards))*s.size)
+	for i := range s.shards {
+		sh := &s.shards[i]
+		var written uint64
+		if reset {
+			written = sh.index.Swap(0)
+			stats.Count += sh.count.Swap(0)
+			stats.Sum += sh.sum.Swap(0)
+			stats.Min = min(stats.Min, sh.min.Swap(math.MaxInt64))
+			stats.Max = max(stats.Max, sh.max.Swap(math.MinInt64))
+		} else {
+			written = sh.index.Load()
+			stats.Count += sh.count.Load()
+			stats.Sum += sh.sum.Load()
+			stats.Min = min(stats.Min, sh.min.Load())
+			stats.Max = max(stats.Max, sh.max.Load())
+		}
+		for j := uint64(0); j < min(written, s.size); j++ {
+			values = append(values, sh.values[j].Load())
+		}
+	}
+	return NewSampleSnapshotWithStats(int64(len(values)), values, stats)
+}
+
+// Update samples a new value.  It takes no lock: the shard is picked with the
+// runtime's per-thread random source and written with atomics.
+func (s *ShardedSample) Update(v int64) {
+	sh := &s.shards[rand.Uint64()&s.mask]
+	if s.size > 0 {
+		i := sh.index.Add(1) - 1
+		sh.values[i%s.size].Store(v)
+	}
+	sh.count.Add(1)
+	sh.sum.Add(v)
+	for cur := sh.min.Load(); v < cur && !sh.min.CompareAndSwap(cur, v); cur = sh.min.Load() {
+	}
+	for cur := sh.max.Load(); v > cur && !sh.max.CompareAndSwap(cur, v); cur = sh.max.Load() {
+	}
+}
@@ -0,0 +1,73 @@
+package sample
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedSample_ConcurrentUpdate(t *testing.T) {
+	s := NewShardedSample(8, 16)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int64(1); i <= 1000; i++ {
+				s.Update(i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	snapshot := s.SnapshotAndReset()
+	assert.Equal(t, int64(8000), snapshot.ReqCount())
+	assert.Equal(t, int64(8*500500), snapshot.ExactSum())
+	assert.Equal(t, int64(1), snapshot.ExactMin())
+	assert.Equal(t, int64(1000), snapshot.ExactMax())
+	assert.LessOrEqual(t, snapshot.Count(), int64(8*16))
+	assert.Equal(t, int(snapshot.Count()), snapshot.Size())
+
+	snapshot = s.Snapshot()
+	assert.Equal(t, int64(0), snapshot.ReqCount())
+	assert.Equal(t, int64(0), snapshot.Count())
+	assert.Equal(t, int64(0), snapshot.ExactMax())
+}
+
+func TestShardedSample_Snapshot(t *testing.T) {
+	s := NewShardedSample(1, 4)
+	for i := int64(1); i <= 6; i++ {
+		s.Update(i)
+	}
+	snapshot := s.Snapshot()
+	assert.Equal(t, int64(6), snapshot.ReqCount())
+	assert.Equal(t, int64(4), snapshot.Count())
+	assert.ElementsMatch(t, []int64{3, 4, 5, 6}, snapshot.(*sampleSnapshot).values)
+	assert.Equal(t, int64(6), s.Snapshot().ReqCount())
+}
+
+// The parallel benchmarks compare the sharded sample with the mutex-based
+// samples; run them with -cpu 1,2,4,8,... to see how each scales.
+func BenchmarkShardedSampleParallel(b *testing.B) {
+	benchmarkSampleParallel(b, NewShardedSample(0, 128))
+}
+
+func BenchmarkSlidingWindowSampleParallel(b *testing.B) {
+	benchmarkSampleParallel(b, NewSlidingWindowSample(1028))
+}
+
+func BenchmarkExpDecaySampleParallel(b *testing.B) {
+	benchmarkSampleParallel(b, NewExpDecaySample(1028, 0.015))
+}
+
+func benchmarkSampleParallel(b *testing.B, s Sample) {
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int64
+		for pb.Next() {
+			s.Update(i)
+			i++
+		}
+	})
+}
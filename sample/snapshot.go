@@ -1,46 +1,106 @@
 package sample
 
-// sampleSnapshot is a read-only copy of another Sample.
+import (
+	"math"
+	"slices"
+	"sync"
+)
+
+// sampleSnapshot is a read-only copy of another Sample.  The first query
+// sorts a copy of the values and computes the summary statistics; later
+// queries are answered from that cache.
 type sampleSnapshot struct {
-	reqCount int64
-	count    int64
-	values   []int64
+	count  int64
+	values []int64
+	stats  Stats
+
+	once     sync.Once
+	sorted   []int64
+	sum      int64
+	mean     float64
+	variance float64
 }
 
 func (s *sampleSnapshot) ReqCount() int64 {
-	return s.reqCount
+	return s.stats.Count
 }
 
+// NewSampleSnapshot constructs a snapshot whose exact statistics are taken
+// from values, with reqCount as the number of updates.  The snapshot keeps
+// values, which must not be modified afterwards.
 func NewSampleSnapshot(reqCount, count int64, values []int64) SampleSnapshot {
+	stats := NewStats(values)
+	stats.Count = reqCount
+	return NewSampleSnapshotWithStats(count, values, stats)
+}
+
+// NewSampleSnapshotWithStats constructs a snapshot from the retained values
+// and the exact statistics over every update.  The snapshot keeps values,
+// which must not be modified afterwards.
+func NewSampleSnapshotWithStats(count int64, values []int64, stats Stats) SampleSnapshot {
 	return &sampleSnapshot{
-		reqCount: reqCount,
-		count:    count,
-		values:   values,
+		count:  count,
+		values: values,
+		stats:  stats,
 	}
 }
 
+// compute sorts a copy of the values and caches the summary statistics.
+func (s *sampleSnapshot) compute() {
+	s.once.Do(func() {
+		s.sorted = slices.Clone(s.values)
+		slices.Sort(s.sorted)
+		s.sum = SampleSum(s.sorted)
+		s.mean = SampleMean(s.sorted)
+		s.variance = SampleVariance(s.sorted)
+	})
+}
+
 // Count returns the count of inputs at the time the snapshot was taken.
 func (s *sampleSnapshot) Count() int64 { return s.count }
 
 // Max returns the maximal value at the time the snapshot was taken.
-func (s *sampleSnapshot) Max() int64 { return SampleMax(s.values) }
+func (s *sampleSnapshot) Max() int64 {
+	s.compute()
+	if len(s.sorted) == 0 {
+		return 0
+	}
+	return s.sorted[len(s.sorted)-1]
+}
 
 // Mean returns the mean value at the time the snapshot was taken.
-func (s *sampleSnapshot) Mean() float64 { return SampleMean(s.values) }
+func (s *sampleSnapshot) Mean() float64 {
+	s.compute()
+	return s.mean
+}
 
 // Min returns the minimal value at the time the snapshot was taken.
-func (s *sampleSnapshot) Min() int64 { return SampleMin(s.values) }
+func (s *sampleSnapshot) Min() int64 {
+	s.compute()
+	if len(s.sorted) == 0 {
+		return 0
+	}
+	return s.sorted[0]
+}
 
 // Percentile returns an arbitrary percentile of values at the time the
 // snapshot was taken.
 func (s *sampleSnapshot) Percentile(p float64) float64 {
-	return SamplePercentile(s.values, p)
+	return s.Percentiles([]float64{p})[0]
 }
 
 // Percentiles returns a slice of arbitrary percentiles of values at the time
 // the snapshot was taken.
 func (s *sampleSnapshot) Percentiles(ps []float64) []float64 {
-	return SamplePercentiles(s.values, ps)
+	s.compute()
+	return sortedPercentiles(s.sorted, ps)
+}
+
+// PercentilesMethod returns a slice of arbitrary percentiles of values at the
+// time the snapshot was taken, estimated with the given method.
+func (s *sampleSnapshot) PercentilesMethod(ps []float64, m QuantileMethod) []float64 {
+	s.compute()
+	return sortedQuantiles(s.sorted, ps, m)
 }
 
 // Size returns the size of the sample at the time the snapshot was taken.
@@ -48,10 +108,57 @@ func (s *sampleSnapshot) Size() int { return len(s.values) }
 
 // StdDev returns the standard deviation of values at the time the snapshot was
 // taken.
-func (s *sampleSnapshot) StdDev() float64 { return SampleStdDev(s.values) }
+func (s *sampleSnapshot) StdDev() float64 { return math.Sqrt(s.Variance()) }
 
 // Sum returns the sum of values at the time the snapshot was taken.
-func (s *sampleSnapshot) Sum() int64 { return SampleSum(s.values) }
+func (s *sampleSnapshot) Sum() int64 {
+	s.compute()
+	return s.sum
+}
+
+// Summary returns every statistic of the snapshot at once.
+func (s *sampleSnapshot) Summary(ps []float64) Summary {
+	s.compute()
+	return Summary{
+		ReqCount:    s.stats.Count,
+		Count:       s.count,
+		Size:        len(s.values),
+		Min:         s.Min(),
+		Max:         s.Max(),
+		Sum:         s.sum,
+		Mean:        s.mean,
+		StdDev:      math.Sqrt(s.variance),
+		Variance:    s.variance,
+		Percentiles: sortedPercentiles(s.sorted, ps),
+		ExactMin:    s.stats.min(),
+		ExactMax:    s.stats.max(),
+		ExactSum:    s.stats.Sum,
+		ExactMean:   s.stats.mean(),
+	}
+}
+
+// Values returns the values retained at the time the snapshot was taken.
+// The slice is shared with the snapshot and must not be modified.
+func (s *sampleSnapshot) Values() []int64 { return s.values }
 
 // Variance returns the variance of values at the time the snapshot was taken.
-func (s *sampleSnapshot) Variance() float64 { return SampleVariance(s.values) }
+func (s *sampleSnapshot) Variance() float64 {
+	s.compute()
+	return s.variance
+}
+
+// ExactMax returns the maximal value passed to Update before the snapshot was
+// taken.
+func (s *sampleSnapshot) ExactMax() int64 { return s.stats.max() }
+
+// ExactMean returns the mean of all values passed to Update before the
+// snapshot was taken.
+func (s *sampleSnapshot) ExactMean() float64 { return s.stats.mean() }
+
+// ExactMin returns the minimal value passed to Update before the snapshot was
+// taken.
+func (s *sampleSnapshot) ExactMin() int64 { return s.stats.min() }
+
+// ExactSum returns the sum of all values passed to Update before the snapshot
+// was taken.
+func (s *sampleSnapshot) ExactSum() int64 { return s.stats.Sum }
@@ -0,0 +1,155 @@
+package sample
+
+import (
+	"math"
+	"slices"
+	"sync"
+)
+
+// float64SampleSnapshot is a read-only copy of another Float64Sample.  The
+// first query sorts a copy of the values and computes the summary
+// statistics; later queries are answered from that cache.
+type float64SampleSnapshot struct {
+	count  int64
+	values []float64
+	stats  Float64Stats
+
+	once     sync.Once
+	sorted   []float64
+	sum      float64
+	mean     float64
+	variance float64
+}
+
+// NewFloat64SampleSnapshot constructs a snapshot from the retained values and
+// the exact statistics over every update.  The snapshot keeps values, which
+// must not be modified afterwards.
+func NewFloat64SampleSnapshot(count int64, values []float64, stats Float64Stats) Float64SampleSnapshot {
+	return &float64SampleSnapshot{
+		count:  count,
+		values: values,
+		stats:  stats,
+	}
+}
+
+// compute sorts a copy of the values and caches the summary statistics.
+func (s *float64SampleSnapshot) compute() {
+	s.once.Do(func() {
+		s.sorted = slices.Clone(s.values)
+		slices.Sort(s.sorted)
+		s.sum = SampleSumFloat64(s.sorted)
+		s.mean = SampleMeanFloat64(s.sorted)
+		s.variance = SampleVarianceFloat64(s.sorted)
+	})
+}
+
+func (s *float64SampleSnapshot) ReqCount() int64 {
+	return s.stats.Count
+}
+
+// Count returns the count of inputs at the time the snapshot was taken.
+func (s *float64SampleSnapshot) Count() int64 { return s.count }
+
+// Max returns the maximal value at the time the snapshot was taken.
+func (s *float64SampleSnapshot) Max() float64 {
+	s.compute()
+	if len(s.sorted) == 0 {
+		return 0
+	}
+	return s.sorted[len(s.sorted)-1]
+}
+
+// Mean returns the mean value at the time the snapshot was taken.
+func (s *float64SampleSnapshot) Mean() float64 {
+	s.compute()
+	return s.mean
+}
+
+// Min returns the minimal value at the time the snapshot was taken.
+func (s *float64SampleSnapshot) Min() float64 {
+	s.compute()
+	if len(s.sorted) == 0 {
+		return 0
+	}
+	return s.sorted[0]
+}
+
+// Percentile returns an arbitrary percentile of values at the time the
+// snapshot was taken.
+func (s *float64SampleSnapshot) Percentile(p float64) float64 {
+	return s.Percentiles([]float64{p})[0]
+}
+
+// Percentiles returns a slice of arbitrary percentiles of values at the time
+// the snapshot was taken.
+func (s *float64SampleSnapshot) Percentiles(ps []float64) []float64 {
+	s.compute()
+	return sortedPercentiles(s.sorted, ps)
+}
+
+// PercentilesMethod returns a slice of arbitrary percentiles of values at the
+// time the snapshot was taken, estimated with the given method.
+func (s *float64SampleSnapshot) PercentilesMethod(ps []float64, m QuantileMethod) []float64 {
+	s.compute()
+	return sortedQuantiles(s.sorted, ps, m)
+}
+
+// Size returns the size of the sample at the time the snapshot was taken.
+func (s *float64SampleSnapshot) Size() int { return len(s.values) }
+
+// StdDev returns the standard deviation of values at the time the snapshot was
+// taken.
+func (s *float64SampleSnapshot) StdDev() float64 { return math.Sqrt(s.Variance()) }
+
+// Sum returns the sum of values at the time the snapshot was taken.
+func (s *float64SampleSnapshot) Sum() float64 {
+	s.compute()
+	return s.sum
+}
+
+// Summary returns every statistic of the snapshot at once.
+func (s *float64SampleSnapshot) Summary(ps []float64) Float64Summary {
+	s.compute()
+	return Float64Summary{
+		ReqCount:    s.stats.Count,
+		Count:       s.count,
+		Size:        len(s.values),
+		Min:         s.Min(),
+		Max:         s.Max(),
+		Sum:         s.sum,
+		Mean:        s.mean,
+		StdDev:      math.Sqrt(s.variance),
+		Variance:    s.variance,
+		Percentiles: sortedPercentiles(s.sorted, ps),
+		ExactMin:    s.stats.min(),
+		ExactMax:    s.stats.max(),
+		ExactSum:    s.stats.Sum,
+		ExactMean:   s.stats.mean(),
+	}
+}
+
+// Values returns the values retained at the time the snapshot was taken.
+// The slice is shared with the snapshot and must not be modified.
+func (s *float64SampleSnapshot) Values() []float64 { return s.values }
+
+// Variance returns the variance of values at the time the snapshot was taken.
+func (s *float64SampleSnapshot) Variance() float64 {
+	s.compute()
+	return s.variance
+}
+
+// ExactMax returns the maximal value passed to Update before the snapshot was
+// taken.
+func (s *float64SampleSnapshot) ExactMax() float64 { return s.stats.max() }
+
+// ExactMean returns the mean of all values passed to Update before the
+// snapshot was taken.
+func (s *float64SampleSnapshot) ExactMean() float64 { return s.stats.mean() }
+
+// ExactMin returns the minimal value passed to Update before the snapshot was
+// taken.
+func (s *float64SampleSnapshot) ExactMin() float64 { return s.stats.min() }
+
+// ExactSum returns the sum of all values passed to Update before the snapshot
+// was taken.
+func (s *float64SampleSnapshot) ExactSum() float64 { return s.stats.Sum }
@@ -0,0 +1,75 @@
+package sample
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleSnapshot_Summary(t *testing.T) {
+	values := []int64{5, 1, 4, 2, 3}
+	s := NewSampleSnapshot(7, 5, values)
+	summary := s.Summary([]float64{0.5, 0.99})
+	assert.Equal(t, Summary{
+		ReqCount:    7,
+		Count:       5,
+		Size:        5,
+		Min:         1,
+		Max:         5,
+		Sum:         15,
+		Mean:        3,
+		StdDev:      s.StdDev(),
+		Variance:    2,
+		Percentiles: []float64{3, 5},
+		ExactMin:    1,
+		ExactMax:    5,
+		ExactSum:    15,
+		ExactMean:   15.0 / 7,
+	}, summary)
+	assert.Equal(t, s.Percentiles([]float64{0.5, 0.99}), summary.Percentiles)
+	// Queries work on a sorted copy and leave the snapshot's values alone.
+	assert.Equal(t, []int64{5, 1, 4, 2, 3}, values)
+}
+
+func TestFloat64SampleSnapshot_Summary(t *testing.T) {
+	values := []float64{2.5, 0.5, 2, 1, 1.5}
+	stats := NewFloat64Stats(values)
+	stats.Count = 10
+	s := NewFloat64SampleSnapshot(5, values, stats)
+	summary := s.Summary([]float64{0.5, 0.99})
+	assert.Equal(t, Float64Summary{
+		ReqCount:    10,
+		Count:       5,
+		Size:        5,
+		Min:         0.5,
+		Max:         2.5,
+		Sum:         7.5,
+		Mean:        1.5,
+		StdDev:      s.StdDev(),
+		Variance:    0.5,
+		Percentiles: []float64{1.5, 2.5},
+		ExactMin:    0.5,
+		ExactMax:    2.5,
+		ExactSum:    7.5,
+		ExactMean:   0.75,
+	}, summary)
+	assert.Equal(t, s.Percentiles([]float64{0.5, 0.99}), summary.Percentiles)
+	assert.Equal(t, []float64{2.5, 0.5, 2, 1, 1.5}, values)
+}
+
+func BenchmarkSnapshotRepeatedQueries(b *testing.B) {
+	values := make([]int64, 1028)
+	for i := range values {
+		values[i] = int64(len(values) - i)
+	}
+	s := NewSampleSnapshot(int64(len(values)), int64(len(values)), values)
+	ps := []float64{0.5, 0.75, 0.95, 0.99, 0.999}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Percentiles(ps)
+		s.Min()
+		s.Max()
+		s.Mean()
+		s.StdDev()
+	}
+}
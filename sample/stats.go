@@ -0,0 +1,159 @@
+package sample
+
+import "math"
+
+// Stats holds exact statistics over every value passed to a sample's Update,
+// regardless of which values the sample retains.
+type Stats struct {
+	Count int64
+	Sum   int64
+	Min   int64
+	Max   int64
+}
+
+// NewStats computes the exact statistics of the given values.
+func NewStats(values []int64) Stats {
+	var s Stats
+	s.reset()
+	for _, v := range values {
+		s.update(v)
+	}
+	return s
+}
+
+// SnapshotStats returns the exact statistics of any snapshot, with the
+// sentinels of NewStats(nil) when no value was recorded.
+func SnapshotStats(s SampleSnapshot) Stats {
+	if s.ReqCount() == 0 {
+		return emptyStats()
+	}
+	return Stats{
+		Count: s.ReqCount(),
+		Sum:   s.ExactSum(),
+		Min:   s.ExactMin(),
+		Max:   s.ExactMax(),
+	}
+}
+
+func (s *Stats) update(v int64) {
+	s.Count++
+	s.Sum += v
+	if v < s.Min {
+		s.Min = v
+	}
+	if v > s.Max {
+		s.Max = v
+	}
+}
+
+func (s *Stats) reset() {
+	s.Count = 0
+	s.Sum = 0
+	s.Min = math.MaxInt64
+	s.Max = math.MinInt64
+}
+
+// merge combines the statistics of two disjoint streams.
+func (s Stats) merge(o Stats) Stats {
+	return Stats{
+		Count: s.Count + o.Count,
+		Sum:   s.Sum + o.Sum,
+		Min:   min(s.Min, o.Min),
+		Max:   max(s.Max, o.Max),
+	}
+}
+
+// min returns the minimal value, or 0 if no value was recorded.
+func (s Stats) min() int64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Min
+}
+
+// max returns the maximal value, or 0 if no value was recorded.
+func (s Stats) max() int64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Max
+}
+
+// mean returns the mean value, or 0 if no value was recorded.
+func (s Stats) mean() float64 {
+	if s.Count == 0 {
+		return 0.0
+	}
+	return float64(s.Sum) / float64(s.Count)
+}
+
+// Float64Stats holds exact statistics over every value passed to a float64
+// sample's Update, regardless of which values the sample retains.
+type Float64Stats struct {
+	Count int64
+	Sum   float64
+	Min   float64
+	Max   float64
+}
+
+// NewFloat64Stats computes the exact statistics of the given values.
+func NewFloat64Stats(values []float64) Float64Stats {
+	var s Float64Stats
+	s.reset()
+	for _, v := range values {
+		s.update(v)
+	}
+	return s
+}
+
+func (s *Float64Stats) update(v float64) {
+	s.Count++
+	s.Sum += v
+	if v < s.Min {
+		s.Min = v
+	}
+	if v > s.Max {
+		s.Max = v
+	}
+}
+
+func (s *Float64Stats) reset() {
+	s.Count = 0
+	s.Sum = 0
+	s.Min = math.Inf(1)
+	s.Max = math.Inf(-1)
+}
+
+// merge combines the statistics of two disjoint streams.
+func (s Float64Stats) merge(o Float64Stats) Float64Stats {
+	return Float64Stats{
+		Count: s.Count + o.Count,
+		Sum:   s.Sum + o.Sum,
+		Min:   math.Min(s.Min, o.Min),
+		Max:   math.Max(s.Max, o.Max),
+	}
+}
+
+// min returns the minimal value, or 0 if no value was recorded.
+func (s Float64Stats) min() float64 {
+	if s.Count == 0 {
+		return 0.0
+	}
+	return s.Min
+}
+
+// max returns the maximal value, or 0 if no value was recorded.
+func (s Float64Stats) max() float64 {
+	if s.Count == 0 {
+		return 0.0
+	}
+	return s.Max
+}
+
+// mean returns the mean value, or 0 if no value was recorded.
+func (s Float64Stats) mean() float64 {
+	if s.Count == 0 {
+		return 0.0
+	}
+	return s.Sum / float64(s.Count)
+}
@@ -0,0 +1,38 @@
+package sample
+
+// Summary holds every statistic of a SampleSnapshot, so that reporters can
+// read them with a single call instead of rescanning the values for each.
+type Summary struct {
+	ReqCount    int64
+	Count       int64
+	Size        int
+	Min         int64
+	Max         int64
+	Sum         int64
+	Mean        float64
+	StdDev      float64
+	Variance    float64
+	Percentiles []float64
+	ExactMin    int64
+	ExactMax    int64
+	ExactSum    int64
+	ExactMean   float64
+}
+
+// Float64Summary holds every statistic of a Float64SampleSnapshot.
+type Float64Summary struct {
+	ReqCount    int64
+	Count       int64
+	Size        int
+	Min         float64
+	Max         float64
+	Sum         float64
+	Mean        float64
+	StdDev      float64
+	Variance    float64
+	Percentiles []float64
+	ExactMin    float64
+	ExactMax    float64
+	ExactSum    float64
+	ExactMean   float64
+}
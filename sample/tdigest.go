@@ -0,0 +1,470 @@
+package sample
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+)
+
+// DefaultTDigestCompression is a compression factor that keeps roughly a
+// hundred centroids while giving sub-percent rank error at the tails.
+const DefaultTDigestCompression = 100
+
+// MaxTDigestCompression bounds the compression factor of decoded digests,
+// whose buffers are sized after it.
+const MaxTDigestCompression = 1e5
+
+const tdigestEncodingVersion = 1
+
+// ErrInvalidTDigest is returned by TDigest.UnmarshalBinary when the input is
+// not a digest produced by TDigest.MarshalBinary.
+var ErrInvalidTDigest = errors.New("tdigest: invalid encoding")
+
+// centroid is a cluster of values summarised by their mean and weight.
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// TDigest is a merging t-digest: a compact sketch of a distribution that is
+// most accurate at the extreme quantiles.  See Dunning & Ertl's "Computing
+// Extremely Accurate Quantiles Using t-Digests".
+//
+// <https://arxiv.org/abs/1902.04023>
+//
+// A TDigest is not safe for concurrent use; TDigestSample wraps one with a
+// mutex.  Digests returned by TDigestSample.Snapshot are read-only copies and
+// implement SampleSnapshot.
+type TDigest struct {
+	compression float64
+	centroids   []centroid
+	buffer      []centroid
+	count       float64
+	sum         float64
+	min, max    float64
+}
+
+// NewTDigest constructs an empty t-digest.  Larger compression factors keep
+// more centroids and give more accurate quantiles.
+func NewTDigest(compression float64) *TDigest {
+	if compression < 1 {
+		compression = DefaultTDigestCompression
+	}
+	return &TDigest{
+		compression: compression,
+		centroids:   make([]centroid, 0, int(compression)),
+		buffer:      make([]centroid, 0, int(compression)*5),
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+// Add adds a single value to the digest.
+func (t *TDigest) Add(v float64) {
+	t.add(v, 1)
+}
+
+func (t *TDigest) add(v, w float64) {
+	if math.IsNaN(v) || w <= 0 {
+		return
+	}
+	t.buffer = append(t.buffer, centroid{mean: v, weight: w})
+	t.count += w
+	t.sum += v * w
+	if v < t.min {
+		t.min = v
+	}
+	if v > t.max {
+		t.max = v
+	}
+	if len(t.buffer) == cap(t.buffer) {
+		t.compress()
+	}
+}
+
+// AddDigest adds every centroid of other into t.  other is left untouched.
+func (t *TDigest) AddDigest(other *TDigest) {
+	if other == nil {
+		return
+	}
+	for _, c := range other.centroids {
+		t.mergeCentroid(c)
+	}
+	for _, c := range other.buffer {
+		t.mergeCentroid(c)
+	}
+	if other.count > 0 {
+		t.min = math.Min(t.min, other.min)
+		t.max = math.Max(t.max, other.max)
+	}
+}
+
+func (t *TDigest) mergeCentroid(c centroid) {
+	t.buffer = append(t.buffer, c)
+	t.count += c.weight
+	t.sum += c.mean * c.weight
+	if len(t.buffer) == cap(t.buffer) {
+		t.compress()
+	}
+}
+
+// Merge returns a new digest combining t and other, leaving both untouched.
+// Merging digests is approximate but keeps the digest's rank-error bounds;
+// min, max, sum and count stay exact.  Other snapshots are folded in by
+// adding each retained value with the weight of the updates it stands for,
+// so their percentiles are only as good as their reservoir; a snapshot that
+// retained no values is added as a single centroid at its mean.
+func (t *TDigest) Merge(other SampleSnapshot) SampleSnapshot {
+	res := t.Clone()
+	switch o := other.(type) {
+	case *TDigest:
+		res.AddDigest(o)
+	case nil:
+	default:
+		n := other.ReqCount()
+		if n == 0 {
+			break
+		}
+		values := snapshotValues(other)
+		if len(values) == 0 {
+			res.buffer = append(res.buffer, centroid{mean: float64(other.ExactSum()) / float64(n), weight: float64(n)})
+		} else {
+			w := float64(n) / float64(len(values))
+			for _, v := range values {
+				res.buffer = append(res.buffer, centroid{mean: float64(v), weight: w})
+			}
+		}
+		res.count += float64(n)
+		res.sum += float64(other.ExactSum())
+		res.min = math.Min(res.min, float64(other.ExactMin()))
+		res.max = math.Max(res.max, float64(other.ExactMax()))
+		res.compress()
+	}
+	return res
+}
+
+// Compression returns the compression factor of the digest.
+func (t *TDigest) Compression() float64 { return t.compression }
+
+// Centroids returns the number of centroids after merging buffered values.
+func (t *TDigest) Centroids() int {
+	t.compress()
+	return len(t.centroids)
+}
+
+// Reset empties the digest, keeping its compression factor.
+func (t *TDigest) Reset() {
+	t.centroids = t.centroids[:0]
+	t.buffer = t.buffer[:0]
+	t.count = 0
+	t.sum = 0
+	t.min = math.Inf(1)
+	t.max = math.Inf(-1)
+}
+
+// Clone returns a deep copy of the digest with all buffered values merged.
+func (t *TDigest) Clone() *TDigest {
+	t.compress()
+	c := NewTDigest(t.compression)
+	c.centroids = append(c.centroids, t.centroids...)
+	c.count = t.count
+	c.sum = t.sum
+	c.min = t.min
+	c.max = t.max
+	return c
+}
+
+// kScale is the k1 scale function, which allows small centroids near q=0 and
+// q=1 and large ones around the median.
+func (t *TDigest) kScale(q float64) float64 {
+	return t.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+// compress merges the buffer into the centroid list.  Adjacent centroids are
+// combined as long as the merged centroid spans at most one unit of k.
+func (t *TDigest) compress() {
+	if len(t.buffer) == 0 {
+		return
+	}
+	all := append(t.buffer, t.centroids...)
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+
+	merged := make([]centroid, 0, cap(t.centroids))
+	cur := all[0]
+	var soFar float64
+	kLow := t.kScale(0)
+	for _, c := range all[1:] {
+		q := (soFar + cur.weight + c.weight) / t.count
+		if t.kScale(q)-kLow <= 1 {
+			cur.weight += c.weight
+			cur.mean += (c.mean - cur.mean) * c.weight / cur.weight
+			continue
+		}
+		merged = append(merged, cur)
+		soFar += cur.weight
+		kLow = t.kScale(soFar / t.count)
+		cur = c
+	}
+	t.centroids = append(merged, cur)
+	t.buffer = all[:0]
+}
+
+// ReqCount returns the number of values added to the digest.
+func (t *TDigest) ReqCount() int64 { return int64(t.count) }
+
+// Count returns the number of values summarised by the digest.
+func (t *TDigest) Count() int64 { return int64(t.count) }
+
+// Max returns the exact maximal value added to the digest.
+func (t *TDigest) Max() int64 {
+	if t.count == 0 {
+		return 0
+	}
+	return int64(t.max)
+}
+
+// Min returns the exact minimal value added to the digest.
+func (t *TDigest) Min() int64 {
+	if t.count == 0 {
+		return 0
+	}
+	return int64(t.min)
+}
+
+// Mean returns the exact mean of the values added to the digest.
+func (t *TDigest) Mean() float64 {
+	if t.count == 0 {
+		return 0.0
+	}
+	return t.sum / t.count
+}
+
+// Sum returns the sum of the values added to the digest.
+func (t *TDigest) Sum() int64 { return int64(math.Round(t.sum)) }
+
+// ExactMax returns the maximal value added to the digest, as does Max.
+func (t *TDigest) ExactMax() int64 { return t.Max() }
+
+// ExactMean returns the mean of the values added to the digest, as does Mean.
+func (t *TDigest) ExactMean() float64 { return t.Mean() }
+
+// ExactMin returns the minimal value added to the digest, as does Min.
+func (t *TDigest) ExactMin() int64 { return t.Min() }
+
+// ExactSum returns the sum of the values added to the digest, as does Sum.
+func (t *TDigest) ExactSum() int64 { return t.Sum() }
+
+// Size returns the number of centroids in the digest.
+func (t *TDigest) Size() int { return t.Centroids() }
+
+// Variance returns the variance of the centroids around the mean.  Spread
+// within each centroid is lost, so this slightly underestimates the variance
+// of the values themselves.
+func (t *TDigest) Variance() float64 {
+	if t.count == 0 {
+		return 0.0
+	}
+	t.compress()
+	m := t.Mean()
+	var sum float64
+	for _, c := range t.centroids {
+		d := c.mean - m
+		sum += c.weight * d * d
+	}
+	return sum / t.count
+}
+
+// StdDev returns the standard deviation estimated by Variance.
+func (t *TDigest) StdDev() float64 { return math.Sqrt(t.Variance()) }
+
+// Percentile returns an estimate of the given percentile (in [0, 1]).
+func (t *TDigest) Percentile(p float64) float64 {
+	return t.Percentiles([]float64{p})[0]
+}
+
+// Percentiles returns estimates of the given percentiles (in [0, 1]).  Each
+// centroid is taken to sit at the middle of its rank range and values are
+// interpolated linearly between neighbouring centroids, and between the
+// outermost centroids and the exact min and max.
+func (t *TDigest) Percentiles(ps []float64) []float64 {
+	scores := make([]float64, len(ps))
+	if t.count == 0 {
+		return scores
+	}
+	t.compress()
+	n := len(t.centroids)
+	mids := make([]float64, n)
+	var cum float64
+	for i, c := range t.centroids {
+		mids[i] = cum + c.weight/2
+		cum += c.weight
+	}
+	for i, p := range ps {
+		scores[i] = t.quantile(p, mids)
+	}
+	return scores
+}
+
+// PercentilesMethod returns the same estimates as Percentiles: a digest keeps
+// no values for the method to apply to, so m is ignored.
+func (t *TDigest) PercentilesMethod(ps []float64, m QuantileMethod) []float64 {
+	return t.Percentiles(ps)
+}
+
+func (t *TDigest) quantile(p float64, mids []float64) float64 {
+	if p <= 0 {
+		return t.min
+	}
+	if p >= 1 {
+		return t.max
+	}
+	n := len(t.centroids)
+	index := p * t.count
+	if index <= mids[0] {
+		first := t.centroids[0]
+		if first.weight == 1 {
+			return first.mean
+		}
+		return t.min + (first.mean-t.min)*index/mids[0]
+	}
+	if index >= mids[n-1] {
+		last := t.centroids[n-1]
+		if last.weight == 1 {
+			return last.mean
+		}
+		return last.mean + (t.max-last.mean)*(index-mids[n-1])/(t.count-mids[n-1])
+	}
+	i := sort.SearchFloat64s(mids, index) - 1
+	lo, hi := t.centroids[i], t.centroids[i+1]
+	return lo.mean + (hi.mean-lo.mean)*(index-mids[i])/(mids[i+1]-mids[i])
+}
+
+// Summary returns every statistic of the digest at once.
+func (t *TDigest) Summary(ps []float64) Summary {
+	variance := t.Variance()
+	return Summary{
+		ReqCount:    t.ReqCount(),
+		Count:       t.Count(),
+		Size:        t.Size(),
+		Min:         t.Min(),
+		Max:         t.Max(),
+		Sum:         t.Sum(),
+		Mean:        t.Mean(),
+		StdDev:      math.Sqrt(variance),
+		Variance:    variance,
+		Percentiles: t.Percentiles(ps),
+		ExactMin:    t.Min(),
+		ExactMax:    t.Max(),
+		ExactSum:    t.Sum(),
+		ExactMean:   t.Mean(),
+	}
+}
+
+// MarshalBinary encodes the digest as a version byte, the compression, count,
+// sum, min and max as big-endian float64s, a uvarint centroid count and each
+// centroid's mean and weight as big-endian float64s.
+func (t *TDigest) MarshalBinary() ([]byte, error) {
+	t.compress()
+	buf := make([]byte, 0, 1+5*8+binary.MaxVarintLen64+len(t.centroids)*16)
+	buf = append(buf, tdigestEncodingVersion)
+	for _, f := range []float64{t.compression, t.count, t.sum, t.min, t.max} {
+		buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(f))
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(t.centroids)))
+	for _, c := range t.centroids {
+		buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(c.mean))
+		buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(c.weight))
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary replaces the digest with one encoded by MarshalBinary.
+func (t *TDigest) UnmarshalBinary(data []byte) error {
+	if len(data) < 1+5*8 || data[0] != tdigestEncodingVersion {
+		return ErrInvalidTDigest
+	}
+	data = data[1:]
+	var header [5]float64
+	for i := range header {
+		header[i] = math.Float64frombits(binary.BigEndian.Uint64(data))
+		data = data[8:]
+	}
+	compression, count := header[0], header[1]
+	if math.IsNaN(compression) || compression < 1 || compression > MaxTDigestCompression {
+		return ErrInvalidTDigest
+	}
+	if math.IsNaN(count) || math.IsInf(count, 0) || count < 0 {
+		return ErrInvalidTDigest
+	}
+	n, read := binary.Uvarint(data)
+	if read <= 0 || n > uint64(len(data)-read)/16 || uint64(len(data)-read) != n*16 {
+		return ErrInvalidTDigest
+	}
+	// Quantiles are interpolated between centroids, so a digest that counts
+	// values must keep at least one.
+	if (count == 0) != (n == 0) {
+		return ErrInvalidTDigest
+	}
+	data = data[read:]
+	d := NewTDigest(compression)
+	d.count, d.sum, d.min, d.max = header[1], header[2], header[3], header[4]
+	for i := uint64(0); i < n; i++ {
+		c := centroid{
+			mean:   math.Float64frombits(binary.BigEndian.Uint64(data)),
+			weight: math.Float64frombits(binary.BigEndian.Uint64(data[8:])),
+		}
+		if math.IsNaN(c.mean) || !(c.weight > 0) || math.IsInf(c.weight, 1) {
+			return ErrInvalidTDigest
+		}
+		d.centroids = append(d.centroids, c)
+		data = data[16:]
+	}
+	*t = *d
+	return nil
+}
+
+// TDigestSample is a Sample backed by a t-digest.  Unlike the reservoir
+// samples it summarises every value, so extreme percentiles such as p99.99
+// stay accurate without storing the values themselves.
+type TDigestSample struct {
+	mutex  sync.Mutex
+	digest *TDigest
+}
+
+// NewTDigestSample constructs a new t-digest sample with the given
+// compression factor.
+func NewTDigestSample(compression float64) Sample {
+	return &TDigestSample{digest: NewTDigest(compression)}
+}
+
+// Clear clears all samples.
+func (s *TDigestSample) Clear() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.digest.Reset()
+}
+
+// Snapshot returns a read-only copy of the sample.  The copy is a *TDigest,
+// so it can be serialised and merged.
+func (s *TDigestSample) Snapshot() SampleSnapshot {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.digest.Clone()
+}
+
+func (s *TDigestSample) SnapshotAndReset() SampleSnapshot {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	res := s.digest.Clone()
+	s.digest.Reset()
+	return res
+}
+
+// Update samples a new value.
+func (s *TDigestSample) Update(v int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.digest.Add(float64(v))
+}
@@ -0,0 +1,189 @@
+package sample
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var tdigestDistributions = map[string]func(r *rand.Rand) int64{
+	"uniform":     func(r *rand.Rand) int64 { return r.Int63n(1000000) },
+	"exponential": func(r *rand.Rand) int64 { return int64(r.ExpFloat64() * 10000) },
+	"normal":      func(r *rand.Rand) int64 { return int64(r.NormFloat64()*1000 + 100000) },
+}
+
+// TestTDigestPercentiles checks that each estimate lies between the exact
+// percentiles a small rank error either side of the requested one: 0.1% in
+// general, tightening to 0.02% beyond p99.9.
+func TestTDigestPercentiles(t *testing.T) {
+	ps := []float64{0.001, 0.01, 0.1, 0.5, 0.9, 0.99, 0.999, 0.9999}
+	for name, gen := range tdigestDistributions {
+		t.Run(name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(1))
+			s := NewTDigestSample(DefaultTDigestCompression)
+			values := make([]int64, 100000)
+			for i := range values {
+				values[i] = gen(r)
+				s.Update(values[i])
+			}
+			snapshot := s.Snapshot()
+			estimates := snapshot.Percentiles(ps)
+			for i, p := range ps {
+				eps := 0.001
+				if p > 0.999 {
+					eps = 0.0002
+				}
+				lo := SamplePercentile(values, math.Max(p-eps, 0))
+				hi := SamplePercentile(values, math.Min(p+eps, 1))
+				assert.GreaterOrEqual(t, estimates[i], lo, "p=%v", p)
+				assert.LessOrEqual(t, estimates[i], hi, "p=%v", p)
+			}
+			assert.Equal(t, int64(len(values)), snapshot.Count())
+			assert.Equal(t, SampleMin(values), snapshot.Min())
+			assert.Equal(t, SampleMax(values), snapshot.Max())
+			assert.InDelta(t, SampleMean(values), snapshot.Mean(), 1e-6)
+			assert.LessOrEqual(t, snapshot.Size(), DefaultTDigestCompression)
+		})
+	}
+}
+
+func TestTDigestSmallSampleIsExact(t *testing.T) {
+	s := NewTDigestSample(DefaultTDigestCompression)
+	for i := int64(1); i <= 10; i++ {
+		s.Update(i)
+	}
+	snapshot := s.Snapshot()
+	assert.Equal(t, 10, snapshot.Size())
+	assert.Equal(t, 1.0, snapshot.Percentile(0))
+	assert.Equal(t, 10.0, snapshot.Percentile(1))
+	assert.Equal(t, 5.5, snapshot.Percentile(0.5))
+}
+
+func TestTDigestMerge(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	all := NewTDigest(DefaultTDigestCompression)
+	merged := NewTDigest(DefaultTDigestCompression)
+	values := make([]int64, 0, 50000)
+	for shard := 0; shard < 5; shard++ {
+		d := NewTDigest(DefaultTDigestCompression)
+		for i := 0; i < 10000; i++ {
+			v := int64(r.ExpFloat64() * 10000)
+			values = append(values, v)
+			d.Add(float64(v))
+			all.Add(float64(v))
+		}
+		merged.AddDigest(d)
+	}
+	assert.Equal(t, all.Count(), merged.Count())
+	assert.Equal(t, all.Min(), merged.Min())
+	assert.Equal(t, all.Max(), merged.Max())
+	for _, p := range []float64{0.5, 0.99, 0.999} {
+		lo := SamplePercentile(values, p-0.005)
+		hi := SamplePercentile(values, p+0.005)
+		assert.GreaterOrEqual(t, merged.Percentile(p), lo, "p=%v", p)
+		assert.LessOrEqual(t, merged.Percentile(p), hi, "p=%v", p)
+	}
+}
+
+func TestTDigestMergeWithoutValues(t *testing.T) {
+	d := NewTDigest(DefaultTDigestCompression)
+	d.Add(10)
+	merged := d.Merge(NewSampleSnapshotWithStats(0, nil, Stats{Count: 3, Sum: 60, Min: 5, Max: 40}))
+	assert.Equal(t, int64(4), merged.ReqCount())
+	assert.Equal(t, int64(70), merged.ExactSum())
+	assert.Equal(t, int64(5), merged.ExactMin())
+	assert.Equal(t, int64(40), merged.ExactMax())
+	assert.Equal(t, 17.5, merged.Mean())
+	assert.Less(t, merged.Percentile(0.25), merged.Percentile(0.75))
+
+	empty := d.Merge(NewSampleSnapshotWithStats(0, nil, Stats{}))
+	assert.Equal(t, int64(1), empty.ReqCount())
+	assert.Equal(t, int64(10), empty.ExactMin())
+}
+
+func TestTDigestMarshalBinary(t *testing.T) {
+	s := NewTDigestSample(50)
+	for i := int64(0); i < 10000; i++ {
+		s.Update(i * i)
+	}
+	snapshot := s.SnapshotAndReset().(*TDigest)
+	data, err := snapshot.MarshalBinary()
+	assert.NoError(t, err)
+
+	var decoded TDigest
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, snapshot.Compression(), decoded.Compression())
+	assert.Equal(t, snapshot.Count(), decoded.Count())
+	assert.Equal(t, snapshot.Sum(), decoded.Sum())
+	ps := []float64{0.01, 0.5, 0.99, 0.9999}
+	assert.Equal(t, snapshot.Percentiles(ps), decoded.Percentiles(ps))
+
+	assert.ErrorIs(t, decoded.UnmarshalBinary(data[:len(data)-1]), ErrInvalidTDigest)
+	assert.ErrorIs(t, decoded.UnmarshalBinary(nil), ErrInvalidTDigest)
+	assert.Equal(t, int64(0), s.Snapshot().Count())
+}
+
+// encodeTDigestHeader encodes a digest header with the given compression
+// followed by a centroid count of n and no centroids.
+func encodeTDigestHeader(compression, count float64, n uint64) []byte {
+	data := []byte{tdigestEncodingVersion}
+	for _, v := range []float64{compression, count, 0, 0, 0} {
+		data = binary.BigEndian.AppendUint64(data, math.Float64bits(v))
+	}
+	return binary.AppendUvarint(data, n)
+}
+
+func encodeTDigest(count float64, centroids ...centroid) []byte {
+	data := encodeTDigestHeader(50, count, uint64(len(centroids)))
+	for _, c := range centroids {
+		data = binary.BigEndian.AppendUint64(data, math.Float64bits(c.mean))
+		data = binary.BigEndian.AppendUint64(data, math.Float64bits(c.weight))
+	}
+	return data
+}
+
+func TestTDigestUnmarshalBinaryMalformed(t *testing.T) {
+	var d TDigest
+	assert.NoError(t, d.UnmarshalBinary(encodeTDigest(0)))
+	assert.NoError(t, d.UnmarshalBinary(encodeTDigest(2, centroid{1, 2})))
+	for name, data := range map[string][]byte{
+		"count overflowing the length": encodeTDigestHeader(50, 0, 1<<60),
+		"count beyond the length":      encodeTDigestHeader(50, 0, 1),
+		"NaN compression":              encodeTDigestHeader(math.NaN(), 0, 0),
+		"infinite compression":         encodeTDigestHeader(math.Inf(1), 0, 0),
+		"negative compression":         encodeTDigestHeader(-1, 0, 0),
+		"huge compression":             encodeTDigestHeader(1e300, 0, 0),
+		"NaN value count":              encodeTDigest(math.NaN()),
+		"negative value count":         encodeTDigest(-1, centroid{1, 1}),
+		"values without centroids":     encodeTDigest(1),
+		"centroids without values":     encodeTDigest(0, centroid{1, 1}),
+		"NaN mean":                     encodeTDigest(1, centroid{math.NaN(), 1}),
+		"zero weight":                  encodeTDigest(1, centroid{1, 0}),
+		"infinite weight":              encodeTDigest(1, centroid{1, math.Inf(1)}),
+	} {
+		assert.ErrorIs(t, d.UnmarshalBinary(data), ErrInvalidTDigest, name)
+	}
+}
+
+func FuzzTDigestUnmarshalBinary(f *testing.F) {
+	d := NewTDigest(20)
+	for i := 0; i < 100; i++ {
+		d.Add(float64(i))
+	}
+	data, _ := d.MarshalBinary()
+	f.Add(data)
+	f.Add(encodeTDigestHeader(50, 0, 1<<60))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var d TDigest
+		if d.UnmarshalBinary(data) == nil {
+			d.Percentiles([]float64{0.5, 0.99})
+		}
+	})
+}
+
+func BenchmarkTDigestSample(b *testing.B) {
+	benchmarkSample(b, NewTDigestSample(DefaultTDigestCompression))
+}
@@ -1,33 +1,37 @@
 package sample
 
 import (
+	"slices"
 	"sync"
 )
 
 // SlidingWindowSample is a sample that stores values in a ring buffer.
 // When get snapshot, it's return snapshot and reset the sampler
 type SlidingWindowSample struct {
-	mutex    sync.Mutex
-	values   []int64
-	size     uint64
-	index    uint64
-	count    int64
-	reqCount int64
+	mutex  sync.Mutex
+	values []int64
+	size   uint64
+	index  uint64
+	count  int64
+	stats  Stats
 }
 
 func (s *SlidingWindowSample) SnapshotAndReset() SampleSnapshot {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	res := NewSampleSnapshot(s.reqCount, s.count, s.values[:s.count])
+	// The snapshot keeps the buffer, which reset replaces.
+	res := NewSampleSnapshotWithStats(s.count, s.values[:s.count], s.stats)
 	s.reset()
 	return res
 }
 
 func NewSlidingWindowSample(size uint64) Sample {
-	return &SlidingWindowSample{
+	s := &SlidingWindowSample{
 		size:   size,
 		values: make([]int64, size),
 	}
+	s.stats.reset()
+	return s
 }
 
 // Clear clears all samples.
@@ -41,22 +45,21 @@ func (s *SlidingWindowSample) Clear() {
 func (s *SlidingWindowSample) Snapshot() SampleSnapshot {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	res := NewSampleSnapshot(s.reqCount, s.count, s.values[:s.count])
-	return res
+	return NewSampleSnapshotWithStats(s.count, slices.Clone(s.values[:s.count]), s.stats)
 }
 
 func (s *SlidingWindowSample) reset() {
 	s.values = make([]int64, s.size)
 	s.index = 0
 	s.count = 0
-	s.reqCount = 0
+	s.stats.reset()
 }
 
 // Update samples a new value.
 func (s *SlidingWindowSample) Update(v int64) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	s.reqCount++
+	s.stats.update(v)
 	s.values[s.index] = v
 	s.index = (s.index + 1) % s.size
 	if s.count < int64(s.size) {
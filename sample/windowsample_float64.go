@@ -0,0 +1,68 @@
+package sample
+
+import (
+	"sync"
+)
+
+// SlidingWindowFloat64Sample is the float64 counterpart of
+// SlidingWindowSample: it stores the most recent values in a ring buffer.
+type SlidingWindowFloat64Sample struct {
+	mutex  sync.Mutex
+	values []float64
+	size   uint64
+	index  uint64
+	count  int64
+	stats  Float64Stats
+}
+
+func NewSlidingWindowFloat64Sample(size uint64) Float64Sample {
+	s := &SlidingWindowFloat64Sample{
+		size:   size,
+		values: make([]float64, size),
+	}
+	s.stats.reset()
+	return s
+}
+
+// Clear clears all samples.
+func (s *SlidingWindowFloat64Sample) Clear() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.reset()
+}
+
+// Snapshot returns a read-only copy of the sample.
+func (s *SlidingWindowFloat64Sample) Snapshot() Float64SampleSnapshot {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	values := make([]float64, s.count)
+	copy(values, s.values)
+	return NewFloat64SampleSnapshot(s.count, values, s.stats)
+}
+
+func (s *SlidingWindowFloat64Sample) SnapshotAndReset() Float64SampleSnapshot {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	res := NewFloat64SampleSnapshot(s.count, s.values[:s.count], s.stats)
+	s.reset()
+	return res
+}
+
+func (s *SlidingWindowFloat64Sample) reset() {
+	s.values = make([]float64, s.size)
+	s.index = 0
+	s.count = 0
+	s.stats.reset()
+}
+
+// Update samples a new value.
+func (s *SlidingWindowFloat64Sample) Update(v float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.stats.update(v)
+	s.values[s.index] = v
+	s.index = (s.index + 1) % s.size
+	if s.count < int64(s.size) {
+		s.count++
+	}
+}
@@ -30,3 +30,60 @@ func TestSlidingWindowSample_Snapshot(t *testing.T) {
 	assert.Equal(t, int64(3), snapshot.ReqCount())
 	assert.Equal(t, int64(1), snapshot.Count())
 }
+
+func TestSlidingWindowSample_SnapshotIsolated(t *testing.T) {
+	s := NewSlidingWindowSample(2)
+	s.Update(1)
+	s.Update(2)
+	snapshot := s.Snapshot()
+	s.Update(9)
+	s.Update(9)
+	assert.Equal(t, []int64{1, 2}, snapshot.(*sampleSnapshot).Values())
+	assert.Equal(t, int64(2), snapshot.Max())
+	assert.Equal(t, 1.5, snapshot.Percentile(0.5))
+}
+
+func TestSlidingWindowSample_ExactStats(t *testing.T) {
+	s := NewSlidingWindowSample(100)
+	for i := int64(1); i <= 10000; i++ {
+		s.Update(i % 1000)
+	}
+	s.Update(-5)
+	s.Update(100000)
+	snapshot := s.SnapshotAndReset()
+	assert.Equal(t, int64(10002), snapshot.ReqCount())
+	assert.Equal(t, int64(100), snapshot.Count())
+	assert.Equal(t, int64(-5), snapshot.ExactMin())
+	assert.Equal(t, int64(100000), snapshot.ExactMax())
+	assert.Equal(t, int64(4995000-5+100000), snapshot.ExactSum())
+	assert.InDelta(t, float64(4995000-5+100000)/10002, snapshot.ExactMean(), 1e-9)
+	assert.Equal(t, SampleSum(snapshot.(*sampleSnapshot).values), snapshot.Sum())
+
+	snapshot = s.Snapshot()
+	assert.Equal(t, int64(0), snapshot.ReqCount())
+	assert.Equal(t, int64(0), snapshot.ExactMin())
+	assert.Equal(t, int64(0), snapshot.ExactMax())
+	assert.Equal(t, 0.0, snapshot.ExactMean())
+}
+
+func TestSlidingWindowFloat64Sample(t *testing.T) {
+	s := NewSlidingWindowFloat64Sample(4)
+	for _, v := range []float64{0.5, 0.25, 1.5, 0.75, 0.125, 2.5} {
+		s.Update(v)
+	}
+	snapshot := s.Snapshot()
+	assert.Equal(t, int64(6), snapshot.ReqCount())
+	assert.Equal(t, int64(4), snapshot.Count())
+	assert.Equal(t, 0.125, snapshot.Min())
+	assert.Equal(t, 2.5, snapshot.Max())
+	assert.Equal(t, 1.21875, snapshot.Mean())
+	assert.Equal(t, 0.125, snapshot.ExactMin())
+	assert.Equal(t, 5.625, snapshot.ExactSum())
+	assert.Equal(t, []float64{0.125, 2.5}, snapshot.Percentiles([]float64{0, 1}))
+	assert.Equal(t, 1.125, snapshot.Percentile(0.5))
+
+	s.Update(10)
+	assert.Equal(t, 1.21875, snapshot.Mean())
+	assert.Equal(t, int64(7), s.SnapshotAndReset().ReqCount())
+	assert.Equal(t, int64(0), s.Snapshot().Count())
+}
@@ -0,0 +1,307 @@
+// Package slo tracks service level objectives, ratios of good to total
+// events over a period, along with their error budget and the rate at which
+// trailing windows burn it.
+package slo
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/someview/go-metrics/clock"
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/internal/instrument"
+	"github.com/someview/go-metrics/internal/ring"
+	"github.com/someview/go-metrics/reporter"
+)
+
+// DefaultPeriod is the period over which the objective is to be met unless
+// set with WithPeriod.
+const DefaultPeriod = 30 * 24 * time.Hour
+
+// DefaultWindows are the burn-rate windows unless set with WithWindows: the
+// pairs 5m/1h and 6h/3d are commonly combined into multi-window alerts.
+var DefaultWindows = []time.Duration{5 * time.Minute, time.Hour, 6 * time.Hour, 3 * 24 * time.Hour}
+
+// WindowBuckets is the number of sub-windows each window rotates through.
+// A window of length d therefore covers between the last 29d/30 and d of
+// events.
+const WindowBuckets = 30
+
+// SLO counts good and total events in the period and in each burn-rate
+// window.  Registered with Register, it is reported as the gauges
+//
+//	checkout.objective                 the objective, e.g. 0.999
+//	checkout.sli                       good/total over the period
+//	checkout.error_budget.remaining    fraction of the period's budget left
+//	checkout.burn_rate.5m              burn rate of each window
+//
+// A burn rate is the window's ratio of bad events divided by the ratio the
+// objective allows, so 1 spends the budget exactly over the period.  Alerts
+// on several windows can be written as rules of package alert, e.g.
+// "checkout.burn_rate.1h > 14.4" together with "checkout.burn_rate.5m >
+// 14.4".
+type SLO struct {
+	objective float64
+	period    time.Duration
+	clock     clock.Clock
+
+	mutex   sync.Mutex
+	total   *window
+	windows []*window
+	sources []*source
+}
+
+type source struct {
+	good, total counter.Counter
+	lastGood    int64
+	lastTotal   int64
+}
+
+type window struct {
+	length time.Duration
+	counts *ring.Ring[counts]
+}
+
+// counts are the good and total events of a bucket of a window.
+type counts struct {
+	good, total int64
+}
+
+func (c *counts) reset() { *c = counts{} }
+
+type options struct {
+	period  time.Duration
+	windows []time.Duration
+	clock   clock.Clock
+}
+
+// Option configures an SLO.
+type Option func(*options)
+
+// WithPeriod sets the period over which the objective is to be met,
+// DefaultPeriod by default.
+func WithPeriod(d time.Duration) Option {
+	return func(o *options) {
+		o.period = d
+	}
+}
+
+// WithWindows sets the burn-rate windows, DefaultWindows by default.
+func WithWindows(windows ...time.Duration) Option {
+	return func(o *options) {
+		o.windows = windows
+	}
+}
+
+// WithClock sets the clock used to rotate windows.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// New constructs an SLO whose objective is the ratio of good events, e.g.
+// 0.999.  Events are added with Add and Record, or read from counters with
+// Track.  It panics unless the objective is between 0 and 1 and windows are
+// at least WindowBuckets nanoseconds long.
+func New(objective float64, opts ...Option) *SLO {
+	if !(objective > 0 && objective < 1) {
+		panic(fmt.Sprintf("slo: objective %v is not between 0 and 1", objective))
+	}
+	o := options{period: DefaultPeriod, windows: DefaultWindows, clock: clock.System()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s := &SLO{objective: objective, period: o.period, clock: o.clock, total: newWindow(o.period)}
+	for _, d := range o.windows {
+		s.windows = append(s.windows, newWindow(d))
+	}
+	return s
+}
+
+// NewFromCounters constructs an SLO tracking the good and total counters, as
+// described for Track.
+func NewFromCounters(good, total counter.Counter, objective float64, opts ...Option) *SLO {
+	s := New(objective, opts...)
+	s.Track(good, total)
+	return s
+}
+
+func newWindow(d time.Duration) *window {
+	if d < WindowBuckets {
+		panic(fmt.Sprintf("slo: window %v is too short", d))
+	}
+	return &window{
+		length: d,
+		counts: ring.New(d, WindowBuckets, func() counts { return counts{} }, (*counts).reset),
+	}
+}
+
+// Objective returns the objective.
+func (s *SLO) Objective() float64 { return s.objective }
+
+// Period returns the period over which the objective is to be met.
+func (s *SLO) Period() time.Duration { return s.period }
+
+// Windows returns the burn-rate window lengths.
+func (s *SLO) Windows() []time.Duration {
+	res := make([]time.Duration, len(s.windows))
+	for i, w := range s.windows {
+		res[i] = w.length
+	}
+	return res
+}
+
+// Add adds good events out of total events.
+func (s *SLO) Add(good, total int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.add(s.clock.Now(), good, total)
+}
+
+// Record adds an event.
+func (s *SLO) Record(good bool) {
+	if good {
+		s.Add(1, 1)
+	} else {
+		s.Add(0, 1)
+	}
+}
+
+// Track adds the increase of the good and total counters each time the SLO
+// is read, attributing it to the time of reading.  Counting starts from the
+// counters' current values, and a counter that decreased, e.g. because a
+// reporter reset it, is taken to have restarted from zero.
+func (s *SLO) Track(good, total counter.Counter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sources = append(s.sources, &source{
+		good:      good,
+		total:     total,
+		lastGood:  good.Snapshot(),
+		lastTotal: total.Snapshot(),
+	})
+}
+
+// Histogram wraps h so that each value it is updated with is also recorded
+// as an event, good if it is at most threshold, e.g. the nanoseconds of a
+// latency objective.
+func (s *SLO) Histogram(h histogram.Histogram, threshold int64) histogram.Histogram {
+	return &thresholdHistogram{Histogram: h, slo: s, threshold: threshold}
+}
+
+type thresholdHistogram struct {
+	histogram.Histogram
+	slo       *SLO
+	threshold int64
+}
+
+func (h *thresholdHistogram) Update(v int64) {
+	h.Histogram.Update(v)
+	h.slo.Record(v <= h.threshold)
+}
+
+// Counts returns the good and total events of the window of length d, or of
+// the period if d is the period.  It returns zeros if there is no such
+// window.
+func (s *SLO) Counts(d time.Duration) (good, total int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := s.clock.Now()
+	s.poll(now)
+	if d == s.period {
+		return s.total.sum(now)
+	}
+	for _, w := range s.windows {
+		if w.length == d {
+			return w.sum(now)
+		}
+	}
+	return 0, 0
+}
+
+// SLI returns the ratio of good events over the period, 1 if there were no
+// events.
+func (s *SLO) SLI() float64 {
+	good, total := s.Counts(s.period)
+	if total == 0 {
+		return 1
+	}
+	return float64(good) / float64(total)
+}
+
+// ErrorBudgetRemaining returns the fraction of the period's error budget
+// that is left, 1 with no bad events and negative once it is overspent.
+func (s *SLO) ErrorBudgetRemaining() float64 {
+	return 1 - s.burnRate(s.Counts(s.period))
+}
+
+// BurnRate returns the burn rate of the window of length d.  It is 0 if the
+// window had no events.
+func (s *SLO) BurnRate(d time.Duration) float64 {
+	return s.burnRate(s.Counts(d))
+}
+
+func (s *SLO) burnRate(good, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(total-good) / float64(total) / (1 - s.objective)
+}
+
+// Register registers the gauges of the SLO in r under name.  If one cannot
+// be registered, none is.
+func (s *SLO) Register(r reporter.Registry, name string) error {
+	gauges := map[string]func() float64{
+		name + ".objective":              s.Objective,
+		name + ".sli":                    s.SLI,
+		name + ".error_budget.remaining": s.ErrorBudgetRemaining,
+	}
+	for _, d := range s.Windows() {
+		gauges[name+".burn_rate."+histogram.WindowName(d)] = func() float64 { return s.BurnRate(d) }
+	}
+	metrics := make(map[string]interface{}, len(gauges))
+	for n, f := range gauges {
+		metrics[n] = guage.NewFunctionalGaugeFloat64(f)
+	}
+	return instrument.Register(r, metrics)
+}
+
+func (s *SLO) add(now time.Time, good, total int64) {
+	s.total.add(now, good, total)
+	for _, w := range s.windows {
+		w.add(now, good, total)
+	}
+}
+
+func (s *SLO) poll(now time.Time) {
+	for _, src := range s.sources {
+		good, total := src.good.Snapshot(), src.total.Snapshot()
+		s.add(now, increase(src.lastGood, good), increase(src.lastTotal, total))
+		src.lastGood, src.lastTotal = good, total
+	}
+}
+
+func increase(last, v int64) int64 {
+	if v < last {
+		return v
+	}
+	return v - last
+}
+
+func (w *window) add(now time.Time, good, total int64) {
+	c := w.counts.At(now)
+	c.good += good
+	c.total += total
+}
+
+func (w *window) sum(now time.Time) (good, total int64) {
+	w.counts.Each(now, func(c *counts) {
+		good += c.good
+		total += c.total
+	})
+	return good, total
+}
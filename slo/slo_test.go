@@ -0,0 +1,133 @@
+package slo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/someview/go-metrics/clock/clocktest"
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/someview/go-metrics/sample"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLO(t *testing.T) {
+	c := clocktest.NewClock(time.Unix(0, 0))
+	s := New(0.99, WithClock(c))
+	assert.Equal(t, 1.0, s.SLI())
+	assert.Equal(t, 1.0, s.ErrorBudgetRemaining())
+	assert.Equal(t, 0.0, s.BurnRate(5*time.Minute))
+
+	// 2% bad events burn the budget twice as fast as allowed.
+	s.Add(98, 100)
+	assert.InDelta(t, 2.0, s.BurnRate(5*time.Minute), 1e-9)
+	assert.InDelta(t, 2.0, s.BurnRate(3*24*time.Hour), 1e-9)
+	assert.InDelta(t, 0.98, s.SLI(), 1e-9)
+	assert.InDelta(t, -1.0, s.ErrorBudgetRemaining(), 1e-9)
+
+	// Good events an hour later dilute the long windows only.
+	c.Add(time.Hour)
+	for i := 0; i < 100; i++ {
+		s.Record(true)
+	}
+	s.Record(false)
+	assert.InDelta(t, 1/1.01, s.BurnRate(5*time.Minute), 1e-9)
+	good, total := s.Counts(time.Hour)
+	assert.Equal(t, int64(100), good)
+	assert.Equal(t, int64(101), total, "the first events left the hour window")
+	good, total = s.Counts(6 * time.Hour)
+	assert.Equal(t, int64(198), good)
+	assert.Equal(t, int64(201), total)
+	assert.InDelta(t, 1-3/2.01, s.ErrorBudgetRemaining(), 1e-9)
+
+	c.Add(DefaultPeriod)
+	assert.Equal(t, 1.0, s.ErrorBudgetRemaining(), "events leave the period")
+	good, total = s.Counts(time.Minute)
+	assert.Zero(t, good+total, "unknown window")
+}
+
+func TestSLO_BeforeUnixEpoch(t *testing.T) {
+	for _, start := range []time.Time{time.Unix(-90, 0), {}} {
+		c := clocktest.NewClock(start)
+		s := New(0.99, WithClock(c))
+		for i := 0; i < 10; i++ {
+			s.Add(9, 10)
+			c.Add(time.Minute)
+		}
+		// The 5m window covers between 4m50s and 5m, which leaves out the
+		// events 5m old.
+		good, total := s.Counts(5 * time.Minute)
+		assert.Equal(t, int64(36), good, start)
+		assert.Equal(t, int64(40), total, start)
+		good, total = s.Counts(time.Hour)
+		assert.Equal(t, int64(90), good, start)
+		assert.Equal(t, int64(100), total, start)
+	}
+}
+
+func TestSLO_Track(t *testing.T) {
+	c := clocktest.NewClock(time.Unix(0, 0))
+	good, total := counter.NewCounter(), counter.NewCounter()
+	good.Inc(1000)
+	total.Inc(1000)
+	s := NewFromCounters(good, total, 0.9, WithClock(c), WithPeriod(24*time.Hour), WithWindows(time.Hour))
+	assert.Equal(t, []time.Duration{time.Hour}, s.Windows())
+	assert.Equal(t, 24*time.Hour, s.Period())
+
+	good.Inc(8)
+	total.Inc(10)
+	assert.InDelta(t, 2.0, s.BurnRate(time.Hour), 1e-9, "counting starts at construction")
+
+	good.SnapshotAndReset()
+	total.SnapshotAndReset()
+	good.Inc(10)
+	total.Inc(10)
+	g, n := s.Counts(24 * time.Hour)
+	assert.Equal(t, int64(18), g)
+	assert.Equal(t, int64(20), n, "resets restart from zero")
+}
+
+func TestSLO_Histogram(t *testing.T) {
+	s := New(0.5)
+	h := s.Histogram(histogram.NewHistogram(sample.NewSlidingWindowSample(10)), int64(250*time.Millisecond))
+	h.Update(int64(100 * time.Millisecond))
+	h.Update(int64(250 * time.Millisecond))
+	h.Update(int64(time.Second))
+	assert.Equal(t, int64(3), h.Sample().Snapshot().ReqCount())
+	good, total := s.Counts(DefaultPeriod)
+	assert.Equal(t, int64(2), good)
+	assert.Equal(t, int64(3), total)
+}
+
+func TestSLO_Register(t *testing.T) {
+	r := reporter.NewRegistry()
+	s := New(0.999)
+	require.NoError(t, s.Register(r, "checkout"))
+	s.Add(0, 1)
+	gauge := func(name string) float64 {
+		return r.Get(name).(guage.GaugeFloat64).SnapshotAndReset()
+	}
+	assert.Equal(t, 0.999, gauge("checkout.objective"))
+	assert.Equal(t, 0.0, gauge("checkout.sli"))
+	assert.InDelta(t, -999.0, gauge("checkout.error_budget.remaining"), 1e-6)
+	assert.InDelta(t, 1000.0, gauge("checkout.burn_rate.5m"), 1e-6)
+	assert.InDelta(t, 1000.0, gauge("checkout.burn_rate.72h"), 1e-6)
+	assert.InDelta(t, 1000.0, gauge("checkout.burn_rate.72h"), 1e-6, "reading does not reset")
+	assert.Error(t, s.Register(r, "checkout"))
+	assert.Equal(t, 0.999, gauge("checkout.objective"), "failing leaves other registrations alone")
+
+	r = reporter.NewRegistry()
+	require.NoError(t, r.Register("checkout.sli", counter.NewCounter()))
+	assert.Error(t, s.Register(r, "checkout"))
+	assert.Nil(t, r.Get("checkout.objective"))
+	assert.Nil(t, r.Get("checkout.burn_rate.5m"))
+}
+
+func TestNew_Invalid(t *testing.T) {
+	assert.Panics(t, func() { New(1) })
+	assert.Panics(t, func() { New(0) })
+	assert.Panics(t, func() { New(0.9, WithWindows(time.Nanosecond)) })
+}
@@ -0,0 +1,258 @@
+package sqlmetrics
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+)
+
+// conn records the operations of a driver connection.  It implements every
+// optional interface database/sql looks for and falls back the way
+// database/sql would when the wrapped connection does not, returning
+// driver.ErrSkip where database/sql has a fallback of its own.
+type conn struct {
+	conn        driver.Conn
+	instruments *instruments
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	start := c.instruments.Clock.Now()
+	var s driver.Stmt
+	var err error
+	if cpc, ok := c.conn.(driver.ConnPrepareContext); ok {
+		s, err = cpc.PrepareContext(ctx, query)
+	} else if err = ctx.Err(); err == nil {
+		s, err = c.conn.Prepare(query)
+	}
+	c.instruments.record(OpPrepare, query, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return newStmt(s, c.conn, query, c.instruments), nil
+}
+
+func (c *conn) Close() error { return c.conn.Close() }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	start := c.instruments.Clock.Now()
+	var tx driver.Tx
+	var err error
+	if cbt, ok := c.conn.(driver.ConnBeginTx); ok {
+		tx, err = cbt.BeginTx(ctx, opts)
+	} else if opts.Isolation != 0 || opts.ReadOnly {
+		err = errors.New("sqlmetrics: driver does not support non-default transaction options")
+	} else if err = ctx.Err(); err == nil {
+		tx, err = c.conn.Begin()
+	}
+	c.instruments.record(OpBegin, "", start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{tx: tx, instruments: c.instruments}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := c.instruments.Clock.Now()
+	var res driver.Result
+	var err error
+	switch ec := c.conn.(type) {
+	case driver.ExecerContext:
+		res, err = ec.ExecContext(ctx, query, args)
+	case driver.Execer:
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			if err = ctx.Err(); err == nil {
+				res, err = ec.Exec(query, values)
+			}
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	c.instruments.record(OpExec, query, start, err)
+	return res, err
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := c.instruments.Clock.Now()
+	var rows driver.Rows
+	var err error
+	switch qc := c.conn.(type) {
+	case driver.QueryerContext:
+		rows, err = qc.QueryContext(ctx, query, args)
+	case driver.Queryer:
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			if err = ctx.Err(); err == nil {
+				rows, err = qc.Query(query, values)
+			}
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	c.instruments.record(OpQuery, query, start, err)
+	return rows, err
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if sr, ok := c.conn.(driver.SessionResetter); ok {
+		return sr.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := c.conn.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// transaction records the end of a transaction.
+type transaction struct {
+	tx          driver.Tx
+	instruments *instruments
+}
+
+func (t *transaction) Commit() error {
+	start := t.instruments.Clock.Now()
+	err := t.tx.Commit()
+	t.instruments.record(OpCommit, "", start, err)
+	return err
+}
+
+func (t *transaction) Rollback() error {
+	start := t.instruments.Clock.Now()
+	err := t.tx.Rollback()
+	t.instruments.record(OpRollback, "", start, err)
+	return err
+}
+
+// stmt records the executions of a prepared statement under its query.
+type stmt struct {
+	stmt        driver.Stmt
+	conn        driver.Conn
+	query       string
+	instruments *instruments
+}
+
+// newStmt wraps a statement prepared on conn, implementing
+// driver.ColumnConverter when s does so that database/sql converts
+// arguments as it would for s.
+func newStmt(s driver.Stmt, conn driver.Conn, query string, i *instruments) driver.Stmt {
+	ws := &stmt{stmt: s, conn: conn, query: query, instruments: i}
+	if _, ok := s.(driver.ColumnConverter); ok {
+		return columnConverterStmt{ws}
+	}
+	return ws
+}
+
+func (s *stmt) Close() error  { return s.stmt.Close() }
+func (s *stmt) NumInput() int { return s.stmt.NumInput() }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	start := s.instruments.Clock.Now()
+	res, err := s.stmt.Exec(args)
+	s.instruments.record(OpExec, s.query, start, err)
+	return res, err
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	start := s.instruments.Clock.Now()
+	rows, err := s.stmt.Query(args)
+	s.instruments.record(OpQuery, s.query, start, err)
+	return rows, err
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	sec, ok := s.stmt.(driver.StmtExecContext)
+	if !ok {
+		values, err := namedValues(args)
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return s.Exec(values)
+	}
+	start := s.instruments.Clock.Now()
+	res, err := sec.ExecContext(ctx, args)
+	s.instruments.record(OpExec, s.query, start, err)
+	return res, err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	sqc, ok := s.stmt.(driver.StmtQueryContext)
+	if !ok {
+		values, err := namedValues(args)
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return s.Query(values)
+	}
+	start := s.instruments.Clock.Now()
+	rows, err := sqc.QueryContext(ctx, args)
+	s.instruments.record(OpQuery, s.query, start, err)
+	return rows, err
+}
+
+// CheckNamedValue checks arguments with the statement's checker, or the
+// connection's, which database/sql would otherwise skip as the statement
+// implements driver.NamedValueChecker.
+func (s *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := s.stmt.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	if nvc, ok := s.conn.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// columnConverterStmt is a stmt whose wrapped statement implements
+// driver.ColumnConverter.
+type columnConverterStmt struct {
+	*stmt
+}
+
+func (s columnConverterStmt) ColumnConverter(idx int) driver.ValueConverter {
+	return s.stmt.stmt.(driver.ColumnConverter).ColumnConverter(idx)
+}
+
+// namedValues converts arguments for the methods of drivers predating named
+// parameters, as database/sql does.
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("sqlmetrics: driver does not support the use of Named Parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,215 @@
+// Package sqlmetrics instruments database/sql drivers and connection pools
+// with metrics kept in a reporter.Registry.
+package sqlmetrics
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/someview/go-metrics/clock"
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/internal/instrument"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/someview/go-metrics/sample"
+)
+
+// Operations timed by a wrapped driver.
+const (
+	OpPrepare  = "prepare"
+	OpExec     = "exec"
+	OpQuery    = "query"
+	OpBegin    = "begin"
+	OpCommit   = "commit"
+	OpRollback = "rollback"
+)
+
+// Option configures a wrapped driver or connector.
+type Option func(*instruments)
+
+// WithPrefix sets the prefix of metric names, "sql" by default.
+func WithPrefix(prefix string) Option {
+	return func(i *instruments) {
+		i.prefix = prefix
+	}
+}
+
+// WithQueryLabel labels prepare, exec and query metrics with the name f
+// gives to their query, e.g. its leading keyword or a name embedded in a
+// comment.  Its results should be few, as every distinct result registers
+// its own metrics.
+func WithQueryLabel(f func(query string) string) Option {
+	return func(i *instruments) {
+		i.label = f
+	}
+}
+
+// WithSample sets the constructor of the samples underlying the latency
+// histograms, sample.NewDefaultSample by default.
+func WithSample(f func() sample.Sample) Option {
+	return func(i *instruments) {
+		i.NewSample = f
+	}
+}
+
+// WithClock sets the clock used to measure latency.
+func WithClock(c clock.Clock) Option {
+	return func(i *instruments) {
+		i.Clock = c
+	}
+}
+
+// StatementKind is a query label function that returns the upper-cased first
+// keyword of a query, such as "SELECT" or "INSERT".
+func StatementKind(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "UNKNOWN"
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// instruments records the operations of a wrapped driver.  Each operation
+// has a latency histogram of nanoseconds and an error counter, named e.g.
+// "sql.exec.latency" and "sql.exec.errors", or "sql.exec.INSERT.latency"
+// with a query label.  driver.ErrSkip, which asks database/sql to fall back
+// to another method, is neither timed nor counted.
+type instruments struct {
+	instrument.Options
+	registry reporter.Registry
+	prefix   string
+	label    func(string) string
+	metrics  sync.Map // name -> *opMetrics
+}
+
+type opMetrics struct {
+	latency histogram.Histogram
+	errors  counter.Counter
+}
+
+func newInstruments(r reporter.Registry, opts []Option) *instruments {
+	i := &instruments{
+		Options:  instrument.DefaultOptions(),
+		registry: r,
+		prefix:   "sql",
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+func (i *instruments) record(op, query string, start time.Time, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	name := op
+	if i.label != nil && query != "" {
+		name += "." + i.label(query)
+	}
+	if i.prefix != "" {
+		name = i.prefix + "." + name
+	}
+	m := i.opMetrics(name)
+	m.latency.Update(int64(i.Clock.Now().Sub(start)))
+	if err != nil {
+		m.errors.Inc(1)
+	}
+}
+
+func (i *instruments) opMetrics(name string) *opMetrics {
+	if m, ok := i.metrics.Load(name); ok {
+		return m.(*opMetrics)
+	}
+	m := &opMetrics{
+		latency: i.Histogram(i.registry, name+".latency"),
+		errors:  reporter.GetOrRegisterCounter(name+".errors", i.registry),
+	}
+	actual, _ := i.metrics.LoadOrStore(name, m)
+	return actual.(*opMetrics)
+}
+
+// Wrap returns a driver whose connections record their operations in r.
+// Register it with sql.Register under a name of its own.
+func Wrap(d driver.Driver, r reporter.Registry, opts ...Option) driver.Driver {
+	return &wrappedDriver{driver: d, instruments: newInstruments(r, opts)}
+}
+
+// WrapConnector returns a connector whose connections record their
+// operations in r, for use with sql.OpenDB.
+func WrapConnector(c driver.Connector, r reporter.Registry, opts ...Option) driver.Connector {
+	i := newInstruments(r, opts)
+	return &wrappedConnector{
+		connector:   c,
+		driver:      &wrappedDriver{driver: c.Driver(), instruments: i},
+		instruments: i,
+	}
+}
+
+type wrappedDriver struct {
+	driver      driver.Driver
+	instruments *instruments
+}
+
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{conn: c, instruments: d.instruments}, nil
+}
+
+func (d *wrappedDriver) OpenConnector(name string) (driver.Connector, error) {
+	dc, ok := d.driver.(driver.DriverContext)
+	if !ok {
+		return &dsnConnector{name: name, driver: d}, nil
+	}
+	c, err := dc.OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedConnector{connector: c, driver: d, instruments: d.instruments}, nil
+}
+
+type wrappedConnector struct {
+	connector   driver.Connector
+	driver      *wrappedDriver
+	instruments *instruments
+}
+
+func (c *wrappedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{conn: dc, instruments: c.instruments}, nil
+}
+
+func (c *wrappedConnector) Driver() driver.Driver { return c.driver }
+
+// Close closes the wrapped connector if it implements io.Closer, as
+// sql.DB.Close does for its connector.
+func (c *wrappedConnector) Close() error {
+	if closer, ok := c.connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// dsnConnector connects drivers that do not implement driver.DriverContext,
+// as database/sql would.
+type dsnConnector struct {
+	name   string
+	driver *wrappedDriver
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.name)
+}
+
+func (c *dsnConnector) Driver() driver.Driver { return c.driver }
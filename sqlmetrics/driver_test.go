@@ -0,0 +1,279 @@
+package sqlmetrics
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/someview/go-metrics/counter"
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/histogram"
+	"github.com/someview/go-metrics/reporter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDriver is an in-memory key-value store understanding three
+// statements: "INSERT ?, ?" stores a value under a key, "SELECT ?" returns
+// the value of a key and "FAIL" fails.  Its connections only implement the
+// mandatory driver interfaces unless withContext is set, and its statements
+// convert arguments with converter when it is set.
+type fakeDriver struct {
+	mutex       sync.Mutex
+	values      map[string]driver.Value
+	withContext bool
+	converter   driver.ValueConverter
+	closed      bool
+}
+
+func newFakeDriver(withContext bool) *fakeDriver {
+	return &fakeDriver{values: make(map[string]driver.Value), withContext: withContext}
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	c := &fakeConn{driver: d}
+	if d.withContext {
+		return &fakeContextConn{c}, nil
+	}
+	return c, nil
+}
+
+func (d *fakeDriver) Connect(context.Context) (driver.Conn, error) { return d.Open("") }
+func (d *fakeDriver) Driver() driver.Driver                        { return d }
+
+func (d *fakeDriver) Close() error {
+	d.closed = true
+	return nil
+}
+
+func (d *fakeDriver) exec(query string, args []driver.Value) (driver.Rows, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	switch strings.ToUpper(strings.Fields(query)[0]) {
+	case "INSERT":
+		d.values[args[0].(string)] = args[1]
+		return nil, nil
+	case "SELECT":
+		return &fakeRows{values: []driver.Value{d.values[args[0].(string)]}}, nil
+	}
+	return nil, errors.New("fake failure")
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	s := &fakeStmt{conn: c, query: query}
+	if c.driver.converter != nil {
+		return &fakeConvertingStmt{s}, nil
+	}
+	return s, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+type fakeContextConn struct {
+	*fakeConn
+}
+
+func (c *fakeContextConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	values, _ := namedValues(args)
+	if _, err := c.driver.exec(query, values); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeContextConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	values, _ := namedValues(args)
+	return c.driver.exec(query, values)
+}
+
+func (c *fakeContextConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if _, err := s.conn.driver.exec(s.query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.driver.exec(s.query, args)
+}
+
+// fakeConvertingStmt converts its arguments with the driver's converter,
+// which database/sql only does when it knows the number of arguments.
+type fakeConvertingStmt struct {
+	*fakeStmt
+}
+
+func (s *fakeConvertingStmt) NumInput() int { return strings.Count(s.query, "?") }
+
+func (s *fakeConvertingStmt) ColumnConverter(int) driver.ValueConverter {
+	return s.conn.driver.converter
+}
+
+// upperConverter converts strings to upper case.
+type upperConverter struct{}
+
+func (upperConverter) ConvertValue(v any) (driver.Value, error) {
+	if s, ok := v.(string); ok {
+		return strings.ToUpper(s), nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	values []driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return []string{"value"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+func count(t *testing.T, r reporter.Registry, name string) int64 {
+	h, ok := r.Get(name).(histogram.Histogram)
+	require.True(t, ok, name)
+	return h.Sample().Snapshot().Count()
+}
+
+func errorCount(t *testing.T, r reporter.Registry, name string) int64 {
+	c, ok := r.Get(name).(counter.Counter)
+	require.True(t, ok, name)
+	return c.Snapshot()
+}
+
+func TestWrapConnector(t *testing.T) {
+	r := reporter.NewRegistry()
+	db := sql.OpenDB(WrapConnector(newFakeDriver(true), r, WithQueryLabel(StatementKind)))
+	defer db.Close()
+
+	_, err := db.Exec("INSERT ?, ?", "a", "1")
+	require.NoError(t, err)
+	var v string
+	require.NoError(t, db.QueryRow("select ?", "a").Scan(&v))
+	assert.Equal(t, "1", v)
+	_, err = db.Exec("FAIL")
+	assert.Error(t, err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+	tx, err = db.Begin()
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	s, err := db.Prepare("SELECT ?")
+	require.NoError(t, err)
+	require.NoError(t, s.QueryRow("a").Scan(&v))
+	require.NoError(t, s.Close())
+
+	assert.Equal(t, int64(1), count(t, r, "sql.exec.INSERT.latency"))
+	assert.Equal(t, int64(0), errorCount(t, r, "sql.exec.INSERT.errors"))
+	assert.Equal(t, int64(1), count(t, r, "sql.exec.FAIL.latency"))
+	assert.Equal(t, int64(1), errorCount(t, r, "sql.exec.FAIL.errors"))
+	assert.Equal(t, int64(2), count(t, r, "sql.query.SELECT.latency"))
+	assert.Equal(t, int64(1), count(t, r, "sql.prepare.SELECT.latency"))
+	assert.Equal(t, int64(2), count(t, r, "sql.begin.latency"))
+	assert.Equal(t, int64(1), count(t, r, "sql.commit.latency"))
+	assert.Equal(t, int64(1), count(t, r, "sql.rollback.latency"))
+}
+
+func TestWrapFallbacks(t *testing.T) {
+	r := reporter.NewRegistry()
+	// sql.Open would need the driver registered under a name, which can only
+	// be done once per process; this is how it opens the database.
+	c, err := Wrap(newFakeDriver(false), r, WithPrefix("db")).(driver.DriverContext).OpenConnector("")
+	require.NoError(t, err)
+	db := sql.OpenDB(c)
+	defer db.Close()
+
+	_, err = db.ExecContext(context.Background(), "INSERT ?, ?", "a", "1")
+	require.NoError(t, err)
+	var v string
+	require.NoError(t, db.QueryRow("SELECT ?", "a").Scan(&v))
+	assert.Equal(t, "1", v)
+	_, err = db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	assert.Error(t, err)
+
+	// Without ExecerContext and QueryerContext, database/sql prepares a
+	// statement for each call.
+	assert.Equal(t, int64(2), count(t, r, "db.prepare.latency"))
+	assert.Equal(t, int64(1), count(t, r, "db.exec.latency"))
+	assert.Equal(t, int64(1), count(t, r, "db.query.latency"))
+	assert.Equal(t, int64(1), errorCount(t, r, "db.begin.errors"))
+}
+
+func TestWrapConnectorClose(t *testing.T) {
+	d := newFakeDriver(true)
+	db := sql.OpenDB(WrapConnector(d, reporter.NewRegistry()))
+	require.NoError(t, db.Close())
+	assert.True(t, d.closed)
+}
+
+func TestWrapColumnConverter(t *testing.T) {
+	d := newFakeDriver(false)
+	d.converter = upperConverter{}
+	db := sql.OpenDB(WrapConnector(d, reporter.NewRegistry()))
+	defer db.Close()
+
+	_, err := db.Exec("INSERT ?, ?", "a", "x")
+	require.NoError(t, err)
+	var v string
+	require.NoError(t, db.QueryRow("SELECT ?", "a").Scan(&v))
+	assert.Equal(t, "X", v)
+}
+
+func TestRegisterDBStats(t *testing.T) {
+	r := reporter.NewRegistry()
+	db := sql.OpenDB(WrapConnector(newFakeDriver(true), r))
+	defer db.Close()
+	db.SetMaxOpenConns(3)
+	require.NoError(t, RegisterDBStats(r, "sql.pool", db))
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), r.Get("sql.pool.connections.max_open").(guage.Gauge).Snapshot())
+	assert.Equal(t, int64(1), r.Get("sql.pool.connections.open").(guage.Gauge).Snapshot())
+	assert.Equal(t, int64(1), r.Get("sql.pool.connections.in_use").(guage.Gauge).Snapshot())
+	require.NoError(t, conn.Close())
+	assert.Equal(t, int64(0), r.Get("sql.pool.connections.in_use").(guage.Gauge).SnapShotAndReset())
+	assert.Equal(t, int64(1), r.Get("sql.pool.connections.idle").(guage.Gauge).Snapshot())
+	assert.Equal(t, int64(0), r.Get("sql.pool.wait.count").(guage.Gauge).Snapshot())
+
+	assert.Error(t, RegisterDBStats(r, "sql.pool", db))
+
+	r = reporter.NewRegistry()
+	require.NoError(t, r.Register("sql.pool.wait.count", counter.NewCounter()))
+	assert.Error(t, RegisterDBStats(r, "sql.pool", db))
+	assert.Nil(t, r.Get("sql.pool.connections.open"))
+}
@@ -0,0 +1,50 @@
+package sqlmetrics
+
+import (
+	"database/sql"
+
+	"github.com/someview/go-metrics/guage"
+	"github.com/someview/go-metrics/internal/instrument"
+	"github.com/someview/go-metrics/reporter"
+)
+
+// RegisterDBStats registers gauges reading the connection pool statistics of
+// db each time they are read, named after prefix, e.g. "sql.pool":
+//
+//	sql.pool.connections.max_open       maximum number of open connections
+//	sql.pool.connections.open           open connections
+//	sql.pool.connections.in_use         connections in use
+//	sql.pool.connections.idle           idle connections
+//	sql.pool.wait.count                 connections waited for
+//	sql.pool.wait.duration              nanoseconds waited for connections
+//	sql.pool.closed.max_idle            connections closed by SetMaxIdleConns
+//	sql.pool.closed.max_idle_time       connections closed by SetConnMaxIdleTime
+//	sql.pool.closed.max_lifetime        connections closed by SetConnMaxLifetime
+//
+// The wait and closed gauges are totals since db was opened.  If one gauge
+// cannot be registered, none is.
+func RegisterDBStats(r reporter.Registry, prefix string, db *sql.DB) error {
+	gauges := []struct {
+		name  string
+		value func(sql.DBStats) int64
+	}{
+		{"connections.max_open", func(s sql.DBStats) int64 { return int64(s.MaxOpenConnections) }},
+		{"connections.open", func(s sql.DBStats) int64 { return int64(s.OpenConnections) }},
+		{"connections.in_use", func(s sql.DBStats) int64 { return int64(s.InUse) }},
+		{"connections.idle", func(s sql.DBStats) int64 { return int64(s.Idle) }},
+		{"wait.count", func(s sql.DBStats) int64 { return s.WaitCount }},
+		{"wait.duration", func(s sql.DBStats) int64 { return int64(s.WaitDuration) }},
+		{"closed.max_idle", func(s sql.DBStats) int64 { return s.MaxIdleClosed }},
+		{"closed.max_idle_time", func(s sql.DBStats) int64 { return s.MaxIdleTimeClosed }},
+		{"closed.max_lifetime", func(s sql.DBStats) int64 { return s.MaxLifetimeClosed }},
+	}
+	metrics := make(map[string]interface{}, len(gauges))
+	for _, g := range gauges {
+		name := g.name
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		metrics[name] = guage.NewFunctionalGauge(func() int64 { return g.value(db.Stats()) })
+	}
+	return instrument.Register(r, metrics)
+}